@@ -0,0 +1,35 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apiutils
+
+import "go.aporeto.io/addedeffect/retry"
+
+type config struct {
+	retryOptions []retry.Option
+}
+
+func newConfig() config {
+	return config{}
+}
+
+// An Option can be used to configure the calls to the meta APIs.
+type Option func(*config)
+
+// OptionRetry sets additional options used to retry the requests, like
+// retry.OptionMaxAttempts, retry.OptionBackoff or retry.OptionClock. They
+// are applied after the default ones, so they can override them.
+// Default is to retry until the context is done, every 3 seconds.
+func OptionRetry(options ...retry.Option) Option {
+	return func(c *config) {
+		c.retryOptions = append(c.retryOptions, options...)
+	}
+}
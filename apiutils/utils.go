@@ -35,7 +35,7 @@ type Version struct {
 }
 
 // GetServiceVersions returns the version of the services.
-func GetServiceVersions(ctx context.Context, api string, tlsConfig *tls.Config) (map[string]Version, error) {
+func GetServiceVersions(ctx context.Context, api string, tlsConfig *tls.Config, options ...Option) (map[string]Version, error) {
 
 	client := &http.Client{
 		Timeout: 10 * time.Second,
@@ -47,18 +47,16 @@ func GetServiceVersions(ctx context.Context, api string, tlsConfig *tls.Config)
 	}
 
 	url := fmt.Sprintf("%s/_meta/versions", api)
-	out, err := retry.Retry(
+	resp, err := retry.Do(
 		ctx,
 		makeJobFunc(client, url),
-		makeRetryFunc("Unable to retrieve versions. Retrying in 3s", url),
+		makeRetryOptions("Unable to retrieve versions. Retrying", url, options)...,
 	)
 
 	if err != nil {
 		return nil, err
 	}
 
-	resp := out.(*http.Response)
-
 	config := map[string]Version{}
 
 	defer resp.Body.Close() // nolint: errcheck
@@ -70,7 +68,7 @@ func GetServiceVersions(ctx context.Context, api string, tlsConfig *tls.Config)
 }
 
 // GetModelVersion returns the version of the services.
-func GetModelVersion(ctx context.Context, api string, tlsConfig *tls.Config) (*Version, error) {
+func GetModelVersion(ctx context.Context, api string, tlsConfig *tls.Config, options ...Option) (*Version, error) {
 
 	client := &http.Client{
 		Timeout: 10 * time.Second,
@@ -82,18 +80,16 @@ func GetModelVersion(ctx context.Context, api string, tlsConfig *tls.Config) (*V
 	}
 
 	url := fmt.Sprintf("%s/_meta/model", api)
-	out, err := retry.Retry(
+	resp, err := retry.Do(
 		ctx,
 		makeJobFunc(client, url),
-		makeRetryFunc("Unable to retrieve model version. Retrying in 3s", url),
+		makeRetryOptions("Unable to retrieve model version. Retrying", url, options)...,
 	)
 
 	if err != nil {
 		return nil, err
 	}
 
-	resp := out.(*http.Response)
-
 	config := &Version{}
 
 	defer resp.Body.Close() // nolint: errcheck
@@ -105,7 +101,7 @@ func GetModelVersion(ctx context.Context, api string, tlsConfig *tls.Config) (*V
 }
 
 // GetConfig returns the additional config exposed by the gateway.
-func GetConfig(ctx context.Context, api string, tlsConfig *tls.Config) (map[string]string, error) {
+func GetConfig(ctx context.Context, api string, tlsConfig *tls.Config, options ...Option) (map[string]string, error) {
 
 	client := &http.Client{
 		Timeout: 10 * time.Second,
@@ -117,18 +113,16 @@ func GetConfig(ctx context.Context, api string, tlsConfig *tls.Config) (map[stri
 	}
 
 	url := fmt.Sprintf("%s/_meta/config", api)
-	out, err := retry.Retry(
+	resp, err := retry.Do(
 		ctx,
 		makeJobFunc(client, url),
-		makeRetryFunc("Unable to retrieve config. Retrying in 3s", url),
+		makeRetryOptions("Unable to retrieve config. Retrying", url, options)...,
 	)
 
 	if err != nil {
 		return nil, err
 	}
 
-	resp := out.(*http.Response)
-
 	config := map[string]string{}
 
 	defer resp.Body.Close() // nolint: errcheck
@@ -140,7 +134,7 @@ func GetConfig(ctx context.Context, api string, tlsConfig *tls.Config) (map[stri
 }
 
 // GetPublicCA returns the public CA used by the api.
-func GetPublicCA(ctx context.Context, api string, tlsConfig *tls.Config) ([]byte, error) {
+func GetPublicCA(ctx context.Context, api string, tlsConfig *tls.Config, options ...Option) ([]byte, error) {
 
 	client := &http.Client{
 		Timeout: 10 * time.Second,
@@ -152,26 +146,24 @@ func GetPublicCA(ctx context.Context, api string, tlsConfig *tls.Config) ([]byte
 	}
 
 	url := fmt.Sprintf("%s/_meta/ca", api)
-	out, err := retry.Retry(
+	resp, err := retry.Do(
 		ctx,
 		makeJobFunc(client, url),
-		makeRetryFunc("Unable to retrieve public ca. Retrying in 3s", url),
+		makeRetryOptions("Unable to retrieve public ca. Retrying", url, options)...,
 	)
 
 	if err != nil {
 		return nil, err
 	}
 
-	resp := out.(*http.Response)
-
 	defer resp.Body.Close() // nolint: errcheck
 	return ioutil.ReadAll(resp.Body)
 }
 
 // GetPublicCAPool returns the public CA used by the api as a *x509.CertPool.
-func GetPublicCAPool(ctx context.Context, api string, tlsConfig *tls.Config) (*x509.CertPool, error) {
+func GetPublicCAPool(ctx context.Context, api string, tlsConfig *tls.Config, options ...Option) (*x509.CertPool, error) {
 
-	cadata, err := GetPublicCA(ctx, api, tlsConfig)
+	cadata, err := GetPublicCA(ctx, api, tlsConfig, options...)
 	if err != nil {
 		return nil, err
 	}
@@ -187,7 +179,7 @@ func GetPublicCAPool(ctx context.Context, api string, tlsConfig *tls.Config) (*x
 }
 
 // GetJWTCert returns the public certificate used to sign jwt.
-func GetJWTCert(ctx context.Context, api string, tlsConfig *tls.Config) ([]byte, error) {
+func GetJWTCert(ctx context.Context, api string, tlsConfig *tls.Config, options ...Option) ([]byte, error) {
 
 	client := &http.Client{
 		Timeout: 10 * time.Second,
@@ -199,26 +191,24 @@ func GetJWTCert(ctx context.Context, api string, tlsConfig *tls.Config) ([]byte,
 	}
 
 	url := fmt.Sprintf("%s/_meta/jwtcert", api)
-	out, err := retry.Retry(
+	resp, err := retry.Do(
 		ctx,
 		makeJobFunc(client, url),
-		makeRetryFunc("Unable to retrieve jwt certificate. Retrying in 3s", url),
+		makeRetryOptions("Unable to retrieve jwt certificate. Retrying", url, options)...,
 	)
 
 	if err != nil {
 		return nil, err
 	}
 
-	resp := out.(*http.Response)
-
 	defer resp.Body.Close() // nolint: errcheck
 	return ioutil.ReadAll(resp.Body)
 }
 
 // GetJWTX509Cert returns the public certificate used to sign jwt as an *x509.Certificate.
-func GetJWTX509Cert(ctx context.Context, api string, tlsConfig *tls.Config) (*x509.Certificate, error) {
+func GetJWTX509Cert(ctx context.Context, api string, tlsConfig *tls.Config, options ...Option) (*x509.Certificate, error) {
 
-	data, err := GetJWTCert(ctx, api, tlsConfig)
+	data, err := GetJWTCert(ctx, api, tlsConfig, options...)
 	if err != nil {
 		return nil, err
 	}
@@ -235,7 +225,7 @@ func GetJWTX509Cert(ctx context.Context, api string, tlsConfig *tls.Config) (*x5
 }
 
 // GetManifestURL returns the url of the manifest.
-func GetManifestURL(ctx context.Context, api string, tlsConfig *tls.Config) ([]byte, error) {
+func GetManifestURL(ctx context.Context, api string, tlsConfig *tls.Config, options ...Option) ([]byte, error) {
 
 	client := &http.Client{
 		Timeout: 10 * time.Second,
@@ -247,24 +237,22 @@ func GetManifestURL(ctx context.Context, api string, tlsConfig *tls.Config) ([]b
 	}
 
 	url := fmt.Sprintf("%s/_meta/manifest", api)
-	out, err := retry.Retry(
+	resp, err := retry.Do(
 		ctx,
 		makeJobFunc(client, url),
-		makeRetryFunc("Unable to retrieve manifest url. Retrying in 3s", url),
+		makeRetryOptions("Unable to retrieve manifest url. Retrying", url, options)...,
 	)
 
 	if err != nil {
 		return nil, err
 	}
 
-	resp := out.(*http.Response)
-
 	defer resp.Body.Close() // nolint: errcheck
 	return ioutil.ReadAll(resp.Body)
 }
 
 // GetGoogleOAuthClientID returns the Google oauth client ID used bby the platform.
-func GetGoogleOAuthClientID(ctx context.Context, api string, tlsConfig *tls.Config) ([]byte, error) {
+func GetGoogleOAuthClientID(ctx context.Context, api string, tlsConfig *tls.Config, options ...Option) ([]byte, error) {
 
 	client := &http.Client{
 		Timeout: 10 * time.Second,
@@ -276,24 +264,22 @@ func GetGoogleOAuthClientID(ctx context.Context, api string, tlsConfig *tls.Conf
 	}
 
 	url := fmt.Sprintf("%s/_meta/googleclientid", api)
-	out, err := retry.Retry(
+	resp, err := retry.Do(
 		ctx,
 		makeJobFunc(client, url),
-		makeRetryFunc("Unable to retrieve google client id. Retrying in 3s", url),
+		makeRetryOptions("Unable to retrieve google client id. Retrying", url, options)...,
 	)
 
 	if err != nil {
 		return nil, err
 	}
 
-	resp := out.(*http.Response)
-
 	defer resp.Body.Close() // nolint: errcheck
 	return ioutil.ReadAll(resp.Body)
 }
 
 // GetTime returns the current time from the api server.
-func GetTime(ctx context.Context, api string, tlsConfig *tls.Config) (time.Time, error) {
+func GetTime(ctx context.Context, api string, tlsConfig *tls.Config, options ...Option) (time.Time, error) {
 
 	client := &http.Client{
 		Timeout: 10 * time.Second,
@@ -305,18 +291,16 @@ func GetTime(ctx context.Context, api string, tlsConfig *tls.Config) (time.Time,
 	}
 
 	url := fmt.Sprintf("%s/_meta/time", api)
-	out, err := retry.Retry(
+	resp, err := retry.Do(
 		ctx,
 		makeJobFunc(client, url),
-		makeRetryFunc("Unable to retrieve time. Retrying in 3s", url),
+		makeRetryOptions("Unable to retrieve time. Retrying", url, options)...,
 	)
 
 	if err != nil {
 		return time.Time{}, err
 	}
 
-	resp := out.(*http.Response)
-
 	defer resp.Body.Close() // nolint: errcheck
 
 	timeBytes, err := ioutil.ReadAll(resp.Body)
@@ -330,11 +314,11 @@ func GetTime(ctx context.Context, api string, tlsConfig *tls.Config) (time.Time,
 	return time.Unix(unixTimeInt, 0), nil
 }
 
-func makeJobFunc(client *http.Client, url string) func() (interface{}, error) {
+func makeJobFunc(client *http.Client, url string) func(context.Context) (*http.Response, error) {
 
-	return func() (interface{}, error) {
+	return func(ctx context.Context) (*http.Response, error) {
 
-		req, err := http.NewRequest(http.MethodGet, url, nil)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 		if err != nil {
 			return nil, err
 		}
@@ -348,14 +332,36 @@ func makeJobFunc(client *http.Client, url string) func() (interface{}, error) {
 			return resp, nil
 		}
 
-		return nil, fmt.Errorf("bad response status: %s", resp.Status)
+		resp.Body.Close() // nolint: errcheck
+
+		return nil, retry.WithRetryAfter(resp, fmt.Errorf("bad response status: %s", resp.Status))
+	}
+}
+
+func makeRetryOptions(message string, url string, options []Option) []retry.Option {
+
+	cfg := newConfig()
+	for _, opt := range options {
+		opt(&cfg)
 	}
+
+	return append(
+		[]retry.Option{
+			retry.OptionHook(makeHook(message, url)),
+			retry.OptionTracing(),
+		},
+		cfg.retryOptions...,
+	)
 }
 
-func makeRetryFunc(message string, url string) func(error) error {
+func makeHook(message string, url string) func(retry.Attempt) {
 
-	return func(err error) error {
-		zap.L().Debug(message, zap.String("url", url), zap.Error(err))
-		return nil
+	return func(a retry.Attempt) {
+		zap.L().Debug(message,
+			zap.String("url", url),
+			zap.Int("attempt", a.Number),
+			zap.Duration("retry-in", a.NextDelay),
+			zap.Error(a.Err),
+		)
 	}
 }
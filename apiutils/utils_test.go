@@ -14,11 +14,15 @@ package apiutils
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"go.aporeto.io/addedeffect/retry"
 )
 
 type testType int
@@ -67,12 +71,8 @@ func makeTestServer() *httptest.Server {
 	}))
 }
 
-// doneCtx returns immediately done Context, so we can test failures without retry
-func doneCtx(ctx context.Context) context.Context {
-	cancelCtx, cancel := context.WithCancel(ctx)
-	cancel()
-	return cancelCtx
-}
+// noRetry makes a single attempt, so we can test failures without retry
+var noRetry = OptionRetry(retry.OptionMaxAttempts(1))
 
 func TestGetTime(t *testing.T) {
 
@@ -132,7 +132,7 @@ func TestGetTime(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			data = &tt.testData
-			got, err := GetTime(doneCtx(tt.args.ctx), tt.args.api, tt.args.tlsConfig)
+			got, err := GetTime(tt.args.ctx, tt.args.api, tt.args.tlsConfig, noRetry)
 			switch data.testType {
 			case goodData:
 				if err != nil {
@@ -209,7 +209,7 @@ func TestGetConfig(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			data = &tt.testData
-			got, err := GetConfig(doneCtx(tt.args.ctx), tt.args.api, tt.args.tlsConfig)
+			got, err := GetConfig(tt.args.ctx, tt.args.api, tt.args.tlsConfig, noRetry)
 			switch data.testType {
 			case goodData:
 				if err != nil {
@@ -289,7 +289,7 @@ func TestGetServiceVersions(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			data = &tt.testData
-			got, err := GetServiceVersions(doneCtx(tt.args.ctx), tt.args.api, tt.args.tlsConfig)
+			got, err := GetServiceVersions(tt.args.ctx, tt.args.api, tt.args.tlsConfig, noRetry)
 			switch data.testType {
 			case goodData:
 				if err != nil {
@@ -369,7 +369,7 @@ func TestGetModelVersion(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			data = &tt.testData
-			got, err := GetModelVersion(doneCtx(tt.args.ctx), tt.args.api, tt.args.tlsConfig)
+			got, err := GetModelVersion(tt.args.ctx, tt.args.api, tt.args.tlsConfig, noRetry)
 			switch data.testType {
 			case goodData:
 				if err != nil {
@@ -433,7 +433,7 @@ func TestGetPublicCA(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			data = &tt.testData
-			got, err := GetPublicCA(doneCtx(tt.args.ctx), tt.args.api, tt.args.tlsConfig)
+			got, err := GetPublicCA(tt.args.ctx, tt.args.api, tt.args.tlsConfig, noRetry)
 			switch data.testType {
 			case goodData:
 				if err != nil {
@@ -497,7 +497,7 @@ func TestGetJWTCert(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			data = &tt.testData
-			got, err := GetJWTCert(doneCtx(tt.args.ctx), tt.args.api, tt.args.tlsConfig)
+			got, err := GetJWTCert(tt.args.ctx, tt.args.api, tt.args.tlsConfig, noRetry)
 			switch data.testType {
 			case goodData:
 				if err != nil {
@@ -561,7 +561,7 @@ func TestGetManifestURL(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			data = &tt.testData
-			got, err := GetManifestURL(doneCtx(tt.args.ctx), tt.args.api, tt.args.tlsConfig)
+			got, err := GetManifestURL(tt.args.ctx, tt.args.api, tt.args.tlsConfig, noRetry)
 			switch data.testType {
 			case goodData:
 				if err != nil {
@@ -625,7 +625,7 @@ func TestGetGoogleOAuthClientID(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			data = &tt.testData
-			got, err := GetGoogleOAuthClientID(doneCtx(tt.args.ctx), tt.args.api, tt.args.tlsConfig)
+			got, err := GetGoogleOAuthClientID(tt.args.ctx, tt.args.api, tt.args.tlsConfig, noRetry)
 			switch data.testType {
 			case goodData:
 				if err != nil {
@@ -643,3 +643,71 @@ func TestGetGoogleOAuthClientID(t *testing.T) {
 		})
 	}
 }
+
+func TestGetTime_Retry(t *testing.T) {
+
+	fakeClock := retry.NewFakeClock(time.Now())
+
+	var calls int32
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("1617114591")) // nolint: errcheck
+	}))
+	defer testServer.Close()
+
+	type result struct {
+		got time.Time
+		err error
+	}
+	results := make(chan result)
+
+	go func() {
+		got, err := GetTime(context.Background(), testServer.URL, nil, OptionRetry(retry.OptionClock(fakeClock)))
+		results <- result{got: got, err: err}
+	}()
+
+	fakeClock.BlockUntil(1)
+	fakeClock.Advance(3 * time.Second)
+	fakeClock.BlockUntil(1)
+	fakeClock.Advance(3 * time.Second)
+
+	r := <-results
+	if r.err != nil {
+		t.Fatalf("GetTime() error = %v", r.err)
+	}
+	if want := time.Unix(1617114591, 0); !r.got.Equal(want) {
+		t.Errorf("GetTime() = %v, want %v", r.got, want)
+	}
+	if n := atomic.LoadInt32(&calls); n != 3 {
+		t.Errorf("GetTime() made %d calls, want 3", n)
+	}
+}
+
+func TestGetTime_Cancel(t *testing.T) {
+
+	stop := make(chan struct{})
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-stop:
+		case <-r.Context().Done():
+		}
+	}))
+	defer testServer.Close()
+	defer close(stop)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := GetTime(ctx, testServer.URL, nil)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("GetTime() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("GetTime() returned after %s, want it to return when the context is done", elapsed)
+	}
+}
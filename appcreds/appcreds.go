@@ -13,8 +13,10 @@ package appcreds
 
 import (
 	"context"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/pem"
+	"errors"
 	"fmt"
 
 	"go.aporeto.io/gaia"
@@ -31,11 +33,26 @@ func NewWithOptions(ctx context.Context, m manipulate.Manipulator, namespace str
 		opt(&cfg)
 	}
 
+	if err := cfg.validate(name, roles); err != nil {
+		return nil, err
+	}
+
+	if cfg.parentNamespace != "" {
+		namespace = cfg.parentNamespace
+	}
+
 	creds := gaia.NewAppCredential()
 	creds.Name = name
 	creds.Roles = roles
 	creds.AuthorizedSubnets = cfg.subnets
-	creds.MaxIssuedTokenValidity = cfg.maxValidity.String()
+	if cfg.maxValidity > 0 {
+		creds.MaxIssuedTokenValidity = cfg.maxValidity.String()
+	}
+	creds.Description = cfg.description
+	creds.Metadata = cfg.metadata
+	creds.Protected = cfg.protected
+	creds.Annotations = cfg.annotations
+	creds.AssociatedTags = cfg.associatedTags
 
 	if err := Create(ctx, m, namespace, creds); err != nil {
 		return nil, err
@@ -79,39 +96,45 @@ func Create(ctx context.Context, m manipulate.Manipulator, namespace string, ac
 //
 // Deprecated: use Create instead
 func NewWithAppCredential(ctx context.Context, m manipulate.Manipulator, template *gaia.AppCredential) (*gaia.AppCredential, error) {
-	fmt.Println("DEPRECATED: NewWithAppCredential is deprecated in favor of Create instead")
 
-	csr, pk, err := makeCSR()
-	if err != nil {
+	warnDeprecated("appcreds.NewWithAppCredential", "appcreds.Create")
+
+	creds := fromTemplate(template)
+
+	if err := Create(ctx, m, template.Namespace, creds); err != nil {
 		return nil, err
 	}
 
-	creds := gaia.NewAppCredential()
-	creds.Name = template.Name
-	creds.Description = template.Description
-	creds.Roles = template.Roles
-	creds.Protected = template.Protected
-	creds.Metadata = template.Metadata
-	creds.AuthorizedSubnets = template.AuthorizedSubnets
-	creds.CSR = string(csr)
+	return creds, nil
+}
 
-	if err := m.Create(
-		manipulate.NewContext(
-			ctx,
-			manipulate.ContextOptionNamespace(template.Namespace),
-		),
-		creds,
-	); err != nil {
+// Renew renews the given appcred.
+// If the renewal fails, the given appcred is left untouched.
+func Renew(ctx context.Context, m manipulate.Manipulator, creds *gaia.AppCredential) (*gaia.AppCredential, error) {
+
+	renewal, err := RenewTransactional(ctx, m, creds)
+	if err != nil {
 		return nil, err
 	}
 
-	creds.Credentials.CertificateKey = base64.StdEncoding.EncodeToString(pk)
+	*creds = *renewal.Current
 
 	return creds, nil
 }
 
-// Renew renews the given appcred.
-func Renew(ctx context.Context, m manipulate.Manipulator, creds *gaia.AppCredential) (*gaia.AppCredential, error) {
+// A Renewal holds both the previous and the current
+// identity of an appcred after a renewal.
+type Renewal struct {
+	Previous *gaia.AppCredential
+	Current  *gaia.AppCredential
+}
+
+// RenewTransactional renews a copy of the given appcred. The given appcred
+// is never modified so its key and certificate remain usable until the
+// caller decides to stop using them, for instance after draining the
+// connections established with the previous identity.
+// If the renewal fails, an error is returned and nothing changed.
+func RenewTransactional(ctx context.Context, m manipulate.Manipulator, creds *gaia.AppCredential) (*Renewal, error) {
 
 	// Then we generate a private key and a CSR from the appcred info.
 	csr, pk, err := makeCSR()
@@ -119,23 +142,52 @@ func Renew(ctx context.Context, m manipulate.Manipulator, creds *gaia.AppCredent
 		return nil, err
 	}
 
-	// And we update the appcred with the csr
-	creds.CSR = string(csr)
+	// And we update a copy of the appcred with the csr
+	next := creds.DeepCopy()
+	next.CSR = string(csr)
 
 	if err = m.Update(
 		manipulate.NewContext(
 			ctx,
-			manipulate.ContextOptionNamespace(creds.Namespace),
+			manipulate.ContextOptionNamespace(next.Namespace),
 		),
-		creds,
+		next,
 	); err != nil {
 		return nil, err
 	}
 
+	if next.Credentials == nil {
+		return nil, errors.New("no credentials returned by the api")
+	}
+
 	// And we write the private key in the appcred.
-	creds.Credentials.CertificateKey = base64.StdEncoding.EncodeToString(pk)
+	next.Credentials.CertificateKey = base64.StdEncoding.EncodeToString(pk)
 
-	return creds, nil
+	return &Renewal{
+		Previous: creds,
+		Current:  next,
+	}, nil
+}
+
+// fromTemplate returns a new *gaia.AppCredential
+// with the user settable fields of the given template.
+func fromTemplate(template *gaia.AppCredential) *gaia.AppCredential {
+
+	creds := gaia.NewAppCredential()
+	creds.Name = template.Name
+	creds.Description = template.Description
+	creds.Roles = template.Roles
+	creds.Protected = template.Protected
+	creds.Metadata = template.Metadata
+	creds.AuthorizedSubnets = template.AuthorizedSubnets
+	creds.Annotations = template.Annotations
+	creds.AssociatedTags = template.AssociatedTags
+
+	if template.MaxIssuedTokenValidity != "" {
+		creds.MaxIssuedTokenValidity = template.MaxIssuedTokenValidity
+	}
+
+	return creds
 }
 
 func makeCSR() (csr []byte, key []byte, err error) {
@@ -157,3 +209,20 @@ func makeCSR() (csr []byte, key []byte, err error) {
 
 	return csr, pem.EncodeToMemory(keyBlock), nil
 }
+
+// decodeCertificate decodes a certificate as
+// stored in the credentials of an appcred.
+func decodeCertificate(data string) (*x509.Certificate, error) {
+
+	pemData, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode certificate: %w", err)
+	}
+
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, errors.New("unable to decode certificate: no pem block found")
+	}
+
+	return x509.ParseCertificate(block.Bytes)
+}
@@ -15,6 +15,7 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
 	"go.aporeto.io/elemental"
@@ -104,6 +105,86 @@ func TestAppCred_New(t *testing.T) {
 	})
 }
 
+func TestAppCred_NewWithOptions(t *testing.T) {
+
+	Convey("Given I have a manipulator", t, func() {
+
+		m := maniptest.NewTestManipulator()
+
+		var ns string
+		m.MockCreate(t, func(ctx manipulate.Context, object elemental.Identifiable) error {
+
+			ns = ctx.Namespace()
+
+			ac := object.(*gaia.AppCredential)
+			ac.ID = "ID"
+			ac.Namespace = ns
+			ac.Credentials = gaia.NewCredential()
+
+			return nil
+		})
+
+		Convey("When I call NewWithOptions with all options", func() {
+
+			c, err := NewWithOptions(
+				context.Background(),
+				m,
+				"/ns",
+				"name",
+				[]string{"@auth:role=role1"},
+				OptionSubnets([]string{"10.0.0.0/8"}),
+				OptionMaxValidity(time.Hour),
+				OptionDescription("description"),
+				OptionMetadata([]string{"random=tag"}),
+				OptionProtected(true),
+				OptionAnnotations(map[string][]string{"a": {"b"}}),
+				OptionAssociatedTags([]string{"c=d"}),
+				OptionParentNamespace("/other"),
+			)
+
+			Convey("Then err should be nil", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("Then the cred should be correct", func() {
+				So(ns, ShouldEqual, "/other")
+				So(c.Name, ShouldEqual, "name")
+				So(c.Namespace, ShouldEqual, "/other")
+				So(c.AuthorizedSubnets, ShouldResemble, []string{"10.0.0.0/8"})
+				So(c.MaxIssuedTokenValidity, ShouldEqual, "1h0m0s")
+				So(c.Description, ShouldEqual, "description")
+				So(c.Metadata, ShouldResemble, []string{"random=tag"})
+				So(c.Protected, ShouldBeTrue)
+				So(c.Annotations, ShouldResemble, map[string][]string{"a": {"b"}})
+				So(c.AssociatedTags, ShouldResemble, []string{"c=d"})
+				So(c.Credentials.CertificateKey, ShouldNotBeEmpty)
+			})
+		})
+
+		Convey("When I call NewWithOptions with an invalid subnet", func() {
+
+			c, err := NewWithOptions(
+				context.Background(),
+				m,
+				"/ns",
+				"name",
+				[]string{"@auth:role=role1"},
+				OptionSubnets([]string{"nope"}),
+			)
+
+			Convey("Then err should not be nil", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "invalid appcred: invalid subnet 'nope': invalid CIDR address: nope")
+			})
+
+			Convey("Then the cred should be nil and the api not called", func() {
+				So(c, ShouldBeNil)
+				So(ns, ShouldBeEmpty)
+			})
+		})
+	})
+}
+
 func TestCreate(t *testing.T) {
 
 	Convey("Given I have a manipulator", t, func() {
@@ -234,10 +315,19 @@ func TestAppCred_NewWithAppCredential(t *testing.T) {
 			template.Metadata = []string{"random=tag"}
 			template.Roles = []string{"role=test"}
 			template.Namespace = "/ns"
+			template.AuthorizedSubnets = []string{"10.0.0.0/8"}
+			template.Annotations = map[string][]string{"a": {"b"}}
+			template.AssociatedTags = []string{"c=d"}
+			template.MaxIssuedTokenValidity = "1h"
 
 			c, err := NewWithAppCredential(context.Background(), m, template)
 
 			Convey("Then credential should have template information", func() {
+				So(c, ShouldNotEqual, template)
+				So(c.AuthorizedSubnets, ShouldResemble, template.AuthorizedSubnets)
+				So(c.Annotations, ShouldResemble, template.Annotations)
+				So(c.AssociatedTags, ShouldResemble, template.AssociatedTags)
+				So(c.MaxIssuedTokenValidity, ShouldEqual, template.MaxIssuedTokenValidity)
 				So(c.Name, ShouldEqual, template.Name)
 				So(c.Description, ShouldEqual, template.Description)
 				So(c.Protected, ShouldEqual, template.Protected)
@@ -394,3 +484,96 @@ func TestAppCred_Renew(t *testing.T) {
 		})
 	})
 }
+
+func TestAppCred_RenewTransactional(t *testing.T) {
+
+	Convey("Given I have a manipulator and an appcred", t, func() {
+
+		m := maniptest.NewTestManipulator()
+
+		ac := gaia.NewAppCredential()
+		ac.ID = "ID"
+		ac.Name = "name"
+		ac.Namespace = "/ns"
+		ac.CSR = "old-csr"
+		ac.Credentials = gaia.NewCredential()
+		ac.Credentials.Certificate = "old-cert"
+		ac.Credentials.CertificateKey = "old-key"
+
+		m.MockUpdate(t, func(ctx manipulate.Context, object elemental.Identifiable) error {
+
+			if ctx.Namespace() != "/ns" {
+				panic("expected ns to be /ns")
+			}
+
+			ac := object.(*gaia.AppCredential)
+			ac.Credentials = gaia.NewCredential()
+			ac.Credentials.Certificate = "new-cert"
+
+			return nil
+		})
+
+		Convey("When I call RenewTransactional", func() {
+
+			r, err := RenewTransactional(context.Background(), m, ac)
+
+			Convey("Then err should be nil", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("Then the previous identity should be untouched", func() {
+				So(r.Previous, ShouldEqual, ac)
+				So(r.Previous.CSR, ShouldEqual, "old-csr")
+				So(r.Previous.Credentials.Certificate, ShouldEqual, "old-cert")
+				So(r.Previous.Credentials.CertificateKey, ShouldEqual, "old-key")
+			})
+
+			Convey("Then the current identity should be renewed", func() {
+				So(r.Current, ShouldNotEqual, ac)
+				So(r.Current.ID, ShouldEqual, "ID")
+				So(r.Current.CSR, ShouldNotEqual, "old-csr")
+				So(r.Current.Credentials.Certificate, ShouldEqual, "new-cert")
+				So(r.Current.Credentials.CertificateKey, ShouldNotBeEmpty)
+				So(r.Current.Credentials.CertificateKey, ShouldNotEqual, "old-key")
+			})
+		})
+
+		Convey("When I call RenewTransactional and the update fails", func() {
+
+			m.MockUpdate(t, func(ctx manipulate.Context, object elemental.Identifiable) error {
+				ac := object.(*gaia.AppCredential)
+				ac.Credentials = gaia.NewCredential()
+				return fmt.Errorf("paf")
+			})
+
+			r, err := RenewTransactional(context.Background(), m, ac)
+
+			Convey("Then err should be correct", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "paf")
+				So(r, ShouldBeNil)
+			})
+
+			Convey("Then the appcred should be untouched", func() {
+				So(ac.CSR, ShouldEqual, "old-csr")
+				So(ac.Credentials.Certificate, ShouldEqual, "old-cert")
+				So(ac.Credentials.CertificateKey, ShouldEqual, "old-key")
+			})
+		})
+
+		Convey("When I call Renew and the update fails", func() {
+
+			m.MockUpdate(t, func(ctx manipulate.Context, object elemental.Identifiable) error {
+				return fmt.Errorf("paf")
+			})
+
+			_, err := Renew(context.Background(), m, ac)
+
+			Convey("Then the appcred should be untouched", func() {
+				So(err, ShouldNotBeNil)
+				So(ac.CSR, ShouldEqual, "old-csr")
+				So(ac.Credentials.CertificateKey, ShouldEqual, "old-key")
+			})
+		})
+	})
+}
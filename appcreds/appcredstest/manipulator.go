@@ -0,0 +1,439 @@
+package appcredstest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"go.aporeto.io/elemental"
+	"go.aporeto.io/gaia"
+	"go.aporeto.io/manipulate"
+)
+
+// An Operation represents a manipulator operation.
+type Operation string
+
+// Various values for Operation.
+const (
+	OperationRetrieveMany Operation = "retrieve-many"
+	OperationRetrieve     Operation = "retrieve"
+	OperationCreate       Operation = "create"
+	OperationUpdate       Operation = "update"
+	OperationDelete       Operation = "delete"
+	OperationDeleteMany   Operation = "delete-many"
+	OperationCount        Operation = "count"
+)
+
+type config struct {
+	apiURL   string
+	validity time.Duration
+}
+
+// An Option can be used to configure a new Manipulator.
+type Option func(*config)
+
+// OptionAPIURL sets the API URL written in the
+// credentials of the appcreds. Default is https://127.0.0.1.
+func OptionAPIURL(url string) Option {
+	return func(c *config) {
+		c.apiURL = url
+	}
+}
+
+// OptionCertificateValidity sets the validity of the
+// certificates issued by the test CA. Default is 24h.
+func OptionCertificateValidity(validity time.Duration) Option {
+	return func(c *config) {
+		c.validity = validity
+	}
+}
+
+// A Manipulator is an in-memory manipulate.Manipulator
+// that stores AppCredentials and signs their CSR
+// with a test CA like the real API would.
+type Manipulator struct {
+	cfg    config
+	ca     *x509.Certificate
+	caKey  crypto.Signer
+	caPEM  []byte
+	serial int64
+
+	appcreds map[string]*gaia.AppCredential
+	errors   map[Operation]error
+
+	lock sync.Mutex
+}
+
+// NewManipulator returns a new *Manipulator
+// backed by a newly generated test CA.
+func NewManipulator(options ...Option) (*Manipulator, error) {
+
+	cfg := config{
+		apiURL:   "https://127.0.0.1",
+		validity: 24 * time.Hour,
+	}
+	for _, opt := range options {
+		opt(&cfg)
+	}
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "appcredstest CA"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(10 * 365 * 24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, caKey.Public(), caKey)
+	if err != nil {
+		return nil, err
+	}
+
+	ca, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Manipulator{
+		cfg:      cfg,
+		ca:       ca,
+		caKey:    caKey,
+		caPEM:    pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		serial:   1,
+		appcreds: map[string]*gaia.AppCredential{},
+		errors:   map[Operation]error{},
+	}, nil
+}
+
+// CA returns the PEM encoded certificate of the test CA.
+func (m *Manipulator) CA() []byte {
+	return m.caPEM
+}
+
+// CertPool returns a *x509.CertPool containing the test CA.
+func (m *Manipulator) CertPool() *x509.CertPool {
+
+	pool := x509.NewCertPool()
+	pool.AddCert(m.ca)
+
+	return pool
+}
+
+// InjectError makes the given operation return the given
+// error until it is called again with a nil error.
+func (m *Manipulator) InjectError(op Operation, err error) {
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if err == nil {
+		delete(m.errors, op)
+		return
+	}
+
+	m.errors[op] = err
+}
+
+// AppCredentials returns a copy of all the stored AppCredentials.
+func (m *Manipulator) AppCredentials() gaia.AppCredentialsList {
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	out := make(gaia.AppCredentialsList, 0, len(m.appcreds))
+	for _, ac := range m.appcreds {
+		out = append(out, ac.DeepCopy())
+	}
+
+	return out
+}
+
+// RetrieveMany is part of the manipulate.Manipulator interface.
+// It returns all the AppCredentials in the namespace of the context,
+// and their children if the context is recursive. Filters are ignored.
+func (m *Manipulator) RetrieveMany(mctx manipulate.Context, dest elemental.Identifiables) error {
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if err := m.errors[OperationRetrieveMany]; err != nil {
+		return err
+	}
+
+	list, ok := dest.(*gaia.AppCredentialsList)
+	if !ok {
+		return unsupported(dest.Identity())
+	}
+
+	for _, ac := range m.appcreds {
+		if inNamespace(ac.Namespace, mctx.Namespace(), mctx.Recursive()) {
+			*list = append(*list, ac.DeepCopy())
+		}
+	}
+
+	return nil
+}
+
+// Retrieve is part of the manipulate.Manipulator interface.
+func (m *Manipulator) Retrieve(mctx manipulate.Context, object elemental.Identifiable) error {
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if err := m.errors[OperationRetrieve]; err != nil {
+		return err
+	}
+
+	ac, ok := object.(*gaia.AppCredential)
+	if !ok {
+		return unsupported(object.Identity())
+	}
+
+	stored, ok := m.appcreds[ac.ID]
+	if !ok {
+		return manipulate.NewErrObjectNotFound(fmt.Sprintf("appcred '%s' not found", ac.ID))
+	}
+
+	*ac = *stored.DeepCopy()
+
+	return nil
+}
+
+// Create is part of the manipulate.Manipulator interface.
+// It signs the CSR of the AppCredential and fills its
+// Credentials, except the private key.
+func (m *Manipulator) Create(mctx manipulate.Context, object elemental.Identifiable) error {
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if err := m.errors[OperationCreate]; err != nil {
+		return err
+	}
+
+	ac, ok := object.(*gaia.AppCredential)
+	if !ok {
+		return unsupported(object.Identity())
+	}
+
+	m.serial++
+	ac.ID = fmt.Sprintf("%024x", m.serial)
+	ac.Namespace = mctx.Namespace()
+	ac.CreateTime = time.Now()
+	ac.UpdateTime = ac.CreateTime
+
+	if err := m.issue(ac); err != nil {
+		return err
+	}
+
+	m.store(ac)
+
+	return nil
+}
+
+// Update is part of the manipulate.Manipulator interface.
+// If the AppCredential has a CSR, it is signed and the
+// Credentials are updated, except the private key.
+func (m *Manipulator) Update(mctx manipulate.Context, object elemental.Identifiable) error {
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if err := m.errors[OperationUpdate]; err != nil {
+		return err
+	}
+
+	ac, ok := object.(*gaia.AppCredential)
+	if !ok {
+		return unsupported(object.Identity())
+	}
+
+	stored, ok := m.appcreds[ac.ID]
+	if !ok {
+		return manipulate.NewErrObjectNotFound(fmt.Sprintf("appcred '%s' not found", ac.ID))
+	}
+
+	ac.Namespace = stored.Namespace
+	ac.CreateTime = stored.CreateTime
+	ac.UpdateTime = time.Now()
+
+	if ac.CSR != "" {
+		if err := m.issue(ac); err != nil {
+			return err
+		}
+	} else {
+		ac.Credentials = stored.DeepCopy().Credentials
+	}
+
+	m.store(ac)
+
+	return nil
+}
+
+// Delete is part of the manipulate.Manipulator interface.
+func (m *Manipulator) Delete(mctx manipulate.Context, object elemental.Identifiable) error {
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if err := m.errors[OperationDelete]; err != nil {
+		return err
+	}
+
+	if _, ok := object.(*gaia.AppCredential); !ok {
+		return unsupported(object.Identity())
+	}
+
+	if _, ok := m.appcreds[object.Identifier()]; !ok {
+		return manipulate.NewErrObjectNotFound(fmt.Sprintf("appcred '%s' not found", object.Identifier()))
+	}
+
+	delete(m.appcreds, object.Identifier())
+
+	return nil
+}
+
+// DeleteMany is part of the manipulate.Manipulator interface.
+func (m *Manipulator) DeleteMany(mctx manipulate.Context, identity elemental.Identity) error {
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if err := m.errors[OperationDeleteMany]; err != nil {
+		return err
+	}
+
+	if identity != gaia.AppCredentialIdentity {
+		return unsupported(identity)
+	}
+
+	for id, ac := range m.appcreds {
+		if inNamespace(ac.Namespace, mctx.Namespace(), mctx.Recursive()) {
+			delete(m.appcreds, id)
+		}
+	}
+
+	return nil
+}
+
+// Count is part of the manipulate.Manipulator interface.
+func (m *Manipulator) Count(mctx manipulate.Context, identity elemental.Identity) (int, error) {
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if err := m.errors[OperationCount]; err != nil {
+		return 0, err
+	}
+
+	if identity != gaia.AppCredentialIdentity {
+		return 0, unsupported(identity)
+	}
+
+	var n int
+	for _, ac := range m.appcreds {
+		if inNamespace(ac.Namespace, mctx.Namespace(), mctx.Recursive()) {
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+// issue signs the CSR of the given appcred
+// and writes the resulting credentials in it.
+func (m *Manipulator) issue(ac *gaia.AppCredential) error {
+
+	block, _ := pem.Decode([]byte(ac.CSR))
+	if block == nil {
+		return errors.New("invalid csr: no pem block found")
+	}
+
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("invalid csr: %w", err)
+	}
+
+	if err := csr.CheckSignature(); err != nil {
+		return fmt.Errorf("invalid csr: %w", err)
+	}
+
+	m.serial++
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(m.serial),
+		Subject: pkix.Name{
+			CommonName:   ac.ID,
+			Organization: []string{ac.Namespace},
+		},
+		NotBefore:   time.Now().Add(-time.Minute),
+		NotAfter:    time.Now().Add(m.cfg.validity),
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, m.ca, csr.PublicKey, m.caKey)
+	if err != nil {
+		return err
+	}
+
+	ac.Credentials = gaia.NewCredential()
+	ac.Credentials.ID = ac.ID
+	ac.Credentials.Name = ac.Name
+	ac.Credentials.Namespace = ac.Namespace
+	ac.Credentials.APIURL = m.cfg.apiURL
+	ac.Credentials.Certificate = base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	ac.Credentials.CertificateAuthority = base64.StdEncoding.EncodeToString(m.caPEM)
+
+	return nil
+}
+
+// store saves a copy of the given appcred
+// without its CSR nor its private key.
+func (m *Manipulator) store(ac *gaia.AppCredential) {
+
+	stored := ac.DeepCopy()
+	stored.CSR = ""
+	if stored.Credentials != nil {
+		stored.Credentials.CertificateKey = ""
+	}
+
+	m.appcreds[ac.ID] = stored
+}
+
+func inNamespace(ns string, parent string, recursive bool) bool {
+
+	if ns == parent {
+		return true
+	}
+
+	if !recursive {
+		return false
+	}
+
+	if parent == "/" {
+		return true
+	}
+
+	return len(ns) > len(parent) && ns[:len(parent)+1] == parent+"/"
+}
+
+func unsupported(identity elemental.Identity) error {
+	return fmt.Errorf("unsupported identity '%s'", identity.Name)
+}
@@ -0,0 +1,164 @@
+package appcredstest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.aporeto.io/addedeffect/appcreds"
+	"go.aporeto.io/gaia"
+	"go.aporeto.io/manipulate"
+)
+
+func TestManipulator(t *testing.T) {
+
+	Convey("Given I have a manipulator", t, func() {
+
+		m, err := NewManipulator(
+			OptionAPIURL("https://api.test"),
+			OptionCertificateValidity(time.Hour),
+		)
+		So(err, ShouldBeNil)
+
+		Convey("When I create an appcred", func() {
+
+			c, err := appcreds.New(context.Background(), m, "/ns", "name", []string{"@auth:role=role1"}, nil)
+
+			Convey("Then err should be nil", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("Then the credentials should be filled like the api does", func() {
+				So(c.ID, ShouldNotBeEmpty)
+				So(c.Namespace, ShouldEqual, "/ns")
+				So(c.Credentials.APIURL, ShouldEqual, "https://api.test")
+				So(c.Credentials.Name, ShouldEqual, "name")
+				So(c.Credentials.Namespace, ShouldEqual, "/ns")
+			})
+
+			Convey("Then the certificate should be signed by the test CA", func() {
+				So(appcreds.Verify(c), ShouldBeNil)
+
+				report, err := appcreds.Inspect(c)
+				So(err, ShouldBeNil)
+				So(report.KeyMatchesCertificate, ShouldBeTrue)
+				So(report.Issuer, ShouldEqual, "CN=appcredstest CA")
+				So(report.RemainingValidity, ShouldBeLessThanOrEqualTo, time.Hour)
+			})
+
+			Convey("Then the appcred should be stored without private material", func() {
+				stored := m.AppCredentials()
+				So(len(stored), ShouldEqual, 1)
+				So(stored[0].ID, ShouldEqual, c.ID)
+				So(stored[0].CSR, ShouldBeEmpty)
+				So(stored[0].Credentials.CertificateKey, ShouldBeEmpty)
+			})
+
+			Convey("When I renew it", func() {
+
+				r, err := appcreds.RenewTransactional(context.Background(), m, c)
+
+				Convey("Then the new certificate should be signed by the test CA", func() {
+					So(err, ShouldBeNil)
+					So(appcreds.Verify(r.Current), ShouldBeNil)
+					So(r.Current.Credentials.Certificate, ShouldNotEqual, r.Previous.Credentials.Certificate)
+				})
+			})
+
+			Convey("When I retrieve it", func() {
+
+				ac := gaia.NewAppCredential()
+				ac.ID = c.ID
+
+				err := m.Retrieve(manipulate.NewContext(context.Background()), ac)
+
+				Convey("Then it should be correct", func() {
+					So(err, ShouldBeNil)
+					So(ac.Name, ShouldEqual, "name")
+					So(ac.Credentials.Certificate, ShouldEqual, c.Credentials.Certificate)
+				})
+			})
+
+			Convey("When I list the appcreds", func() {
+
+				list := gaia.AppCredentialsList{}
+				err := m.RetrieveMany(manipulate.NewContext(context.Background(), manipulate.ContextOptionNamespace("/ns")), &list)
+				So(err, ShouldBeNil)
+
+				other := gaia.AppCredentialsList{}
+				err = m.RetrieveMany(manipulate.NewContext(context.Background(), manipulate.ContextOptionNamespace("/other")), &other)
+				So(err, ShouldBeNil)
+
+				recursive := gaia.AppCredentialsList{}
+				err = m.RetrieveMany(
+					manipulate.NewContext(
+						context.Background(),
+						manipulate.ContextOptionNamespace("/"),
+						manipulate.ContextOptionRecursive(true),
+					),
+					&recursive,
+				)
+				So(err, ShouldBeNil)
+
+				Convey("Then the namespaces should be honored", func() {
+					So(len(list), ShouldEqual, 1)
+					So(len(other), ShouldEqual, 0)
+					So(len(recursive), ShouldEqual, 1)
+				})
+			})
+
+			Convey("When I delete it", func() {
+
+				err := appcreds.Delete(context.Background(), m, c)
+
+				Convey("Then it should be gone", func() {
+					So(err, ShouldBeNil)
+					So(len(m.AppCredentials()), ShouldEqual, 0)
+				})
+
+				Convey("Then deleting it again should fail", func() {
+					err := appcreds.Delete(context.Background(), m, c)
+					So(manipulate.IsObjectNotFoundError(err), ShouldBeTrue)
+				})
+			})
+		})
+
+		Convey("When I inject an error", func() {
+
+			m.InjectError(OperationCreate, errors.New("boom"))
+
+			_, err := appcreds.New(context.Background(), m, "/ns", "name", []string{"@auth:role=role1"}, nil)
+
+			Convey("Then the error should be returned", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "boom")
+			})
+
+			Convey("When I remove the error", func() {
+
+				m.InjectError(OperationCreate, nil)
+
+				_, err := appcreds.New(context.Background(), m, "/ns", "name", []string{"@auth:role=role1"}, nil)
+
+				Convey("Then err should be nil", func() {
+					So(err, ShouldBeNil)
+				})
+			})
+		})
+
+		Convey("When I create an appcred with an invalid csr", func() {
+
+			ac := gaia.NewAppCredential()
+			ac.CSR = "nope"
+
+			err := m.Create(manipulate.NewContext(context.Background()), ac)
+
+			Convey("Then err should be correct", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "invalid csr: no pem block found")
+			})
+		})
+	})
+}
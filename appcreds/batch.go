@@ -0,0 +1,117 @@
+package appcreds
+
+import (
+	"context"
+	"sync"
+
+	"go.aporeto.io/gaia"
+	"go.aporeto.io/manipulate"
+)
+
+// A Spec describes an appcred to provision with NewBatch.
+type Spec struct {
+	Namespace string
+	Name      string
+	Roles     []string
+	Options   []Option
+}
+
+// A BatchResult holds the outcome of
+// the provisioning of a single Spec.
+type BatchResult struct {
+	Spec       Spec
+	Credential *gaia.AppCredential
+	Err        error
+}
+
+type batchConfig struct {
+	concurrency int
+	progress    func(done int, total int, result BatchResult)
+}
+
+func newBatchConfig() batchConfig {
+	return batchConfig{
+		concurrency: 4,
+	}
+}
+
+// A BatchOption can be used to configure NewBatch.
+type BatchOption func(*batchConfig)
+
+// BatchOptionConcurrency sets the maximum number of appcreds
+// provisioned at the same time. Default is 4.
+func BatchOptionConcurrency(concurrency int) BatchOption {
+	return func(c *batchConfig) {
+		c.concurrency = concurrency
+	}
+}
+
+// BatchOptionProgress sets a function called every time
+// the provisioning of a spec is done. Calls are serialized.
+func BatchOptionProgress(progress func(done int, total int, result BatchResult)) BatchOption {
+	return func(c *batchConfig) {
+		c.progress = progress
+	}
+}
+
+// NewBatch provisions an appcred for each of the given specs using
+// NewWithOptions, with a bounded concurrency. A failure does not abort
+// the provisioning of the other specs. It returns one BatchResult per
+// spec, in the same order. If the context is done, the specs that were
+// not yet provisioned fail with the error of the context.
+func NewBatch(ctx context.Context, m manipulate.Manipulator, specs []Spec, options ...BatchOption) []BatchResult {
+
+	cfg := newBatchConfig()
+	for _, opt := range options {
+		opt(&cfg)
+	}
+
+	if cfg.concurrency < 1 {
+		cfg.concurrency = 1
+	}
+
+	results := make([]BatchResult, len(specs))
+	indexes := make(chan int)
+
+	var done int
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+
+	for i := 0; i < cfg.concurrency && i < len(specs); i++ {
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for idx := range indexes {
+
+				spec := specs[idx]
+				result := BatchResult{Spec: spec}
+
+				if err := ctx.Err(); err != nil {
+					result.Err = err
+				} else {
+					result.Credential, result.Err = NewWithOptions(ctx, m, spec.Namespace, spec.Name, spec.Roles, spec.Options...)
+				}
+
+				results[idx] = result
+
+				lock.Lock()
+				done++
+				if cfg.progress != nil {
+					cfg.progress(done, len(specs), result)
+				}
+				lock.Unlock()
+			}
+		}()
+	}
+
+	for i := range specs {
+		indexes <- i
+	}
+	close(indexes)
+
+	wg.Wait()
+
+	return results
+}
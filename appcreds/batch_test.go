@@ -0,0 +1,117 @@
+package appcreds
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.aporeto.io/elemental"
+	"go.aporeto.io/gaia"
+	"go.aporeto.io/manipulate"
+	"go.aporeto.io/manipulate/maniptest"
+)
+
+func TestNewBatch(t *testing.T) {
+
+	Convey("Given I have a manipulator and some specs", t, func() {
+
+		m := maniptest.NewTestManipulator()
+
+		var running, maxRunning int32
+		m.MockCreate(t, func(ctx manipulate.Context, object elemental.Identifiable) error {
+
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				max := atomic.LoadInt32(&maxRunning)
+				if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+
+			ac := object.(*gaia.AppCredential)
+			if ac.Name == "bad" {
+				return fmt.Errorf("boom")
+			}
+
+			ac.ID = ac.Name
+			ac.Namespace = ctx.Namespace()
+			ac.Credentials = gaia.NewCredential()
+
+			return nil
+		})
+
+		var specs []Spec
+		for i := 0; i < 10; i++ {
+			name := fmt.Sprintf("app%d", i)
+			if i == 3 {
+				name = "bad"
+			}
+			specs = append(specs, Spec{
+				Namespace: fmt.Sprintf("/ns/%d", i),
+				Name:      name,
+				Roles:     []string{"@auth:role=role1"},
+			})
+		}
+
+		Convey("When I call NewBatch", func() {
+
+			var progress []int
+			var totals []int
+			results := NewBatch(
+				context.Background(), m, specs,
+				BatchOptionConcurrency(3),
+				BatchOptionProgress(func(done int, total int, result BatchResult) {
+					progress = append(progress, done)
+					totals = append(totals, total)
+				}),
+			)
+
+			Convey("Then the concurrency should be bounded", func() {
+				So(maxRunning, ShouldBeLessThanOrEqualTo, 3)
+				So(maxRunning, ShouldBeGreaterThan, 1)
+			})
+
+			Convey("Then the results should be correct", func() {
+				So(len(results), ShouldEqual, 10)
+				for i, r := range results {
+					So(r.Spec.Name, ShouldEqual, specs[i].Name)
+					if i == 3 {
+						So(r.Err, ShouldNotBeNil)
+						So(r.Err.Error(), ShouldEqual, "boom")
+						So(r.Credential, ShouldBeNil)
+						continue
+					}
+					So(r.Err, ShouldBeNil)
+					So(r.Credential.ID, ShouldEqual, specs[i].Name)
+					So(r.Credential.Namespace, ShouldEqual, specs[i].Namespace)
+					So(r.Credential.Credentials.CertificateKey, ShouldNotBeEmpty)
+				}
+			})
+
+			Convey("Then the progress should be reported", func() {
+				So(progress, ShouldResemble, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10})
+				So(totals, ShouldResemble, []int{10, 10, 10, 10, 10, 10, 10, 10, 10, 10})
+			})
+		})
+
+		Convey("When I call NewBatch with a canceled context", func() {
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			results := NewBatch(ctx, m, specs)
+
+			Convey("Then all results should fail", func() {
+				So(len(results), ShouldEqual, 10)
+				for _, r := range results {
+					So(r.Err, ShouldEqual, context.Canceled)
+				}
+			})
+		})
+	})
+}
@@ -0,0 +1,23 @@
+package appcreds
+
+import (
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+var deprecationWarnings sync.Map
+
+// warnDeprecated logs a warning the first time the
+// given deprecated function is called in the process.
+func warnDeprecated(function string, replacement string) {
+
+	if _, loaded := deprecationWarnings.LoadOrStore(function, struct{}{}); loaded {
+		return
+	}
+
+	zap.L().Warn("Deprecated function called",
+		zap.String("function", function),
+		zap.String("replacement", replacement),
+	)
+}
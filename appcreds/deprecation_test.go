@@ -0,0 +1,34 @@
+package appcreds
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestWarnDeprecated(t *testing.T) {
+
+	Convey("Given I have an observed logger", t, func() {
+
+		core, logs := observer.New(zapcore.DebugLevel)
+		defer zap.ReplaceGlobals(zap.New(core))()
+
+		Convey("When I call warnDeprecated many times", func() {
+
+			warnDeprecated("test.Function", "test.Other")
+			warnDeprecated("test.Function", "test.Other")
+			warnDeprecated("test.Function", "test.Other")
+
+			Convey("Then the warning should be logged once", func() {
+				So(logs.Len(), ShouldEqual, 1)
+				entry := logs.All()[0]
+				So(entry.Level, ShouldEqual, zapcore.WarnLevel)
+				So(entry.ContextMap()["function"], ShouldEqual, "test.Function")
+				So(entry.ContextMap()["replacement"], ShouldEqual, "test.Other")
+			})
+		})
+	})
+}
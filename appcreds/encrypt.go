@@ -0,0 +1,325 @@
+package appcreds
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+
+	"go.aporeto.io/gaia"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	encryptedKeyBlockType = "APPCRED ENCRYPTED KEY"
+	encryptedKeyVersion   = "1"
+
+	kdfScrypt  = "scrypt"
+	kdfKeyFile = "keyfile"
+
+	keyFileSize = 32
+)
+
+// Scrypt parameters used for new encryptions. They are
+// stored along with the encrypted key so they can change
+// without breaking the decryption of existing keys.
+var (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+
+	maxScryptN = 1 << 20
+	maxScryptR = 32
+	maxScryptP = 16
+
+	// maxScryptMemory bounds the memory used by scrypt,
+	// which is 128 * N * R bytes.
+	maxScryptMemory = 1 << 30
+)
+
+// A KeyProtection protects the private key of an appcred.
+//
+// newParameters returns the headers needed to derive the key of a
+// new encryption. deriveKey only reads the given headers so that
+// a tampered or truncated block fails instead of getting new ones.
+type KeyProtection interface {
+	kdf() string
+	newParameters() (map[string]string, error)
+	deriveKey(headers map[string]string) ([]byte, error)
+}
+
+type passphraseProtection struct {
+	passphrase []byte
+}
+
+// ProtectWithPassphrase returns a KeyProtection deriving
+// the encryption key from the given passphrase using scrypt.
+func ProtectWithPassphrase(passphrase []byte) KeyProtection {
+	return passphraseProtection{passphrase: passphrase}
+}
+
+func (p passphraseProtection) kdf() string { return kdfScrypt }
+
+func (p passphraseProtection) newParameters() (map[string]string, error) {
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	return map[string]string{
+		"Salt": base64.StdEncoding.EncodeToString(salt),
+		"N":    strconv.Itoa(scryptN),
+		"R":    strconv.Itoa(scryptR),
+		"P":    strconv.Itoa(scryptP),
+	}, nil
+}
+
+func (p passphraseProtection) deriveKey(headers map[string]string) ([]byte, error) {
+
+	if len(p.passphrase) == 0 {
+		return nil, errors.New("empty passphrase")
+	}
+
+	if err := requireHeaders(headers, "Salt", "N", "R", "P"); err != nil {
+		return nil, err
+	}
+
+	salt, err := base64.StdEncoding.DecodeString(headers["Salt"])
+	if err != nil || len(salt) == 0 {
+		return nil, errors.New("invalid salt")
+	}
+
+	bounds := []int{maxScryptN, maxScryptR, maxScryptP}
+	params := make([]int, 3)
+	for i, name := range []string{"N", "R", "P"} {
+		if params[i], err = strconv.Atoi(headers[name]); err != nil {
+			return nil, fmt.Errorf("invalid scrypt parameter %s: %w", name, err)
+		}
+		if params[i] < 1 || params[i] > bounds[i] {
+			return nil, fmt.Errorf("invalid scrypt parameter %s: must be between 1 and %d", name, bounds[i])
+		}
+	}
+
+	if 128*params[0]*params[1] > maxScryptMemory {
+		return nil, fmt.Errorf("invalid scrypt parameters: N and R would use more than %d bytes", maxScryptMemory)
+	}
+
+	return scrypt.Key(p.passphrase, salt, params[0], params[1], params[2], 32)
+}
+
+type keyFileProtection struct {
+	path string
+}
+
+// ProtectWithKeyFile returns a KeyProtection using the content of
+// the given file as encryption key. The file must contain at least
+// 32 bytes. GenerateKeyFile can be used to create one.
+func ProtectWithKeyFile(path string) KeyProtection {
+	return keyFileProtection{path: path}
+}
+
+func (p keyFileProtection) kdf() string { return kdfKeyFile }
+
+func (p keyFileProtection) newParameters() (map[string]string, error) {
+	return map[string]string{}, nil
+}
+
+func (p keyFileProtection) deriveKey(headers map[string]string) ([]byte, error) {
+
+	data, err := ioutil.ReadFile(p.path) // #nosec
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < keyFileSize {
+		return nil, fmt.Errorf("key file must contain at least %d bytes", keyFileSize)
+	}
+
+	key := sha256.Sum256(data)
+
+	return key[:], nil
+}
+
+// GenerateKeyFile writes a new random key file
+// usable with ProtectWithKeyFile at the given path.
+func GenerateKeyFile(path string) error {
+
+	data := make([]byte, keyFileSize)
+	if _, err := rand.Read(data); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+// IsKeyEncrypted returns true if the private key
+// of the given credentials is encrypted.
+func IsKeyEncrypted(creds *gaia.Credential) bool {
+
+	block, err := decodeKeyBlock(creds.CertificateKey)
+	if err != nil {
+		return false
+	}
+
+	return block.Type == encryptedKeyBlockType
+}
+
+// EncryptKey encrypts the private key of the given credentials
+// using AES-GCM with a key obtained from the given KeyProtection.
+// The encrypted key is stored in place of the clear one, in a
+// versioned format containing what is needed to decrypt it.
+func EncryptKey(creds *gaia.Credential, protection KeyProtection) error {
+
+	if IsKeyEncrypted(creds) {
+		return errors.New("private key is already encrypted")
+	}
+
+	plain, err := base64.StdEncoding.DecodeString(creds.CertificateKey)
+	if err != nil {
+		return fmt.Errorf("unable to decode private key: %w", err)
+	}
+
+	headers, err := protection.newParameters()
+	if err != nil {
+		return err
+	}
+	headers["Version"] = encryptedKeyVersion
+	headers["KDF"] = protection.kdf()
+
+	key, err := protection.deriveKey(headers)
+	if err != nil {
+		return err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	headers["Nonce"] = base64.StdEncoding.EncodeToString(nonce)
+
+	block := &pem.Block{
+		Type:    encryptedKeyBlockType,
+		Headers: headers,
+		Bytes:   gcm.Seal(nil, nonce, plain, additionalData(headers)),
+	}
+
+	creds.CertificateKey = base64.StdEncoding.EncodeToString(pem.EncodeToMemory(block))
+
+	return nil
+}
+
+// DecryptKey decrypts the private key of the given credentials
+// previously encrypted by EncryptKey using the given KeyProtection.
+// The clear key is stored in place of the encrypted one.
+func DecryptKey(creds *gaia.Credential, protection KeyProtection) error {
+
+	block, err := decodeKeyBlock(creds.CertificateKey)
+	if err != nil {
+		return err
+	}
+
+	if block.Type != encryptedKeyBlockType {
+		return errors.New("private key is not encrypted")
+	}
+
+	if err := requireHeaders(block.Headers, "Version", "KDF", "Nonce"); err != nil {
+		return err
+	}
+
+	if v := block.Headers["Version"]; v != encryptedKeyVersion {
+		return fmt.Errorf("unsupported encrypted key version '%s'", v)
+	}
+
+	if kdf := block.Headers["KDF"]; kdf != protection.kdf() {
+		return fmt.Errorf("private key has been encrypted using '%s' protection", kdf)
+	}
+
+	key, err := protection.deriveKey(block.Headers)
+	if err != nil {
+		return err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+
+	nonce, err := base64.StdEncoding.DecodeString(block.Headers["Nonce"])
+	if err != nil || len(nonce) != gcm.NonceSize() {
+		return errors.New("invalid nonce")
+	}
+
+	plain, err := gcm.Open(nil, nonce, block.Bytes, additionalData(block.Headers))
+	if err != nil {
+		return errors.New("unable to decrypt private key: wrong protection or corrupted data")
+	}
+
+	creds.CertificateKey = base64.StdEncoding.EncodeToString(plain)
+
+	return nil
+}
+
+// requireHeaders returns an error if any of the given
+// headers is missing from the encrypted key block.
+func requireHeaders(headers map[string]string, names ...string) error {
+
+	for _, name := range names {
+		if _, ok := headers[name]; !ok {
+			return fmt.Errorf("invalid encrypted key: missing header '%s'", name)
+		}
+	}
+
+	return nil
+}
+
+func decodeKeyBlock(data string) (*pem.Block, error) {
+
+	pemData, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode private key: %w", err)
+	}
+
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, errors.New("unable to decode private key: no pem block found")
+	}
+
+	return block, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(c)
+}
+
+// additionalData authenticates the headers describing
+// how the key was encrypted so they cannot be tampered with.
+func additionalData(headers map[string]string) []byte {
+
+	return []byte(fmt.Sprintf(
+		"%s|%s|%s|%s|%s|%s",
+		headers["Version"],
+		headers["KDF"],
+		headers["Salt"],
+		headers["N"],
+		headers["R"],
+		headers["P"],
+	))
+}
@@ -0,0 +1,206 @@
+package appcreds
+
+import (
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestEncryptKey(t *testing.T) {
+
+	scryptN = 1 << 10
+
+	Convey("Given I have credentials", t, func() {
+
+		creds := makeTestCredentials(time.Now().Add(time.Hour))
+		clearKey := creds.CertificateKey
+
+		Convey("When I encrypt the key with a passphrase", func() {
+
+			err := EncryptKey(creds, ProtectWithPassphrase([]byte("secret")))
+
+			Convey("Then the key should be encrypted", func() {
+				So(err, ShouldBeNil)
+				So(IsKeyEncrypted(creds), ShouldBeTrue)
+				So(creds.CertificateKey, ShouldNotEqual, clearKey)
+			})
+
+			Convey("Then the format should be versioned", func() {
+				data, _ := base64.StdEncoding.DecodeString(creds.CertificateKey)
+				block, _ := pem.Decode(data)
+				So(block.Headers["Version"], ShouldEqual, "1")
+				So(block.Headers["KDF"], ShouldEqual, "scrypt")
+				So(block.Headers["N"], ShouldEqual, "1024")
+			})
+
+			Convey("Then the key should not be usable", func() {
+				_, err := TLSConfig(creds)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "unable to decode private key: key is encrypted")
+			})
+
+			Convey("Then encrypting it again should fail", func() {
+				err := EncryptKey(creds, ProtectWithPassphrase([]byte("secret")))
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "private key is already encrypted")
+			})
+
+			Convey("When I decrypt it with the same passphrase", func() {
+
+				err := DecryptKey(creds, ProtectWithPassphrase([]byte("secret")))
+
+				Convey("Then the key should be restored", func() {
+					So(err, ShouldBeNil)
+					So(IsKeyEncrypted(creds), ShouldBeFalse)
+					So(creds.CertificateKey, ShouldEqual, clearKey)
+				})
+			})
+
+			Convey("When I decrypt it after the scrypt parameters changed", func() {
+
+				scryptN = 1 << 11
+				defer func() { scryptN = 1 << 10 }()
+
+				err := DecryptKey(creds, ProtectWithPassphrase([]byte("secret")))
+
+				Convey("Then the key should be restored", func() {
+					So(err, ShouldBeNil)
+					So(creds.CertificateKey, ShouldEqual, clearKey)
+				})
+			})
+
+			Convey("When I decrypt it with another passphrase", func() {
+
+				err := DecryptKey(creds, ProtectWithPassphrase([]byte("nope")))
+
+				Convey("Then err should be correct", func() {
+					So(err, ShouldNotBeNil)
+					So(err.Error(), ShouldEqual, "unable to decrypt private key: wrong protection or corrupted data")
+					So(IsKeyEncrypted(creds), ShouldBeTrue)
+				})
+			})
+
+			Convey("When a header has been removed", func() {
+
+				data, _ := base64.StdEncoding.DecodeString(creds.CertificateKey)
+				block, _ := pem.Decode(data)
+				delete(block.Headers, "Salt")
+				creds.CertificateKey = base64.StdEncoding.EncodeToString(pem.EncodeToMemory(block))
+
+				err := DecryptKey(creds, ProtectWithPassphrase([]byte("secret")))
+
+				Convey("Then err should be correct", func() {
+					So(err, ShouldNotBeNil)
+					So(err.Error(), ShouldEqual, "invalid encrypted key: missing header 'Salt'")
+				})
+			})
+
+			Convey("When a scrypt parameter is too large", func() {
+
+				data, _ := base64.StdEncoding.DecodeString(creds.CertificateKey)
+				block, _ := pem.Decode(data)
+				block.Headers["R"] = "1024"
+				creds.CertificateKey = base64.StdEncoding.EncodeToString(pem.EncodeToMemory(block))
+
+				err := DecryptKey(creds, ProtectWithPassphrase([]byte("secret")))
+
+				Convey("Then err should be correct", func() {
+					So(err, ShouldNotBeNil)
+					So(err.Error(), ShouldEqual, "invalid scrypt parameter R: must be between 1 and 32")
+				})
+			})
+
+			Convey("When the scrypt parameters would use too much memory", func() {
+
+				data, _ := base64.StdEncoding.DecodeString(creds.CertificateKey)
+				block, _ := pem.Decode(data)
+				block.Headers["N"] = "1048576"
+				block.Headers["R"] = "32"
+				creds.CertificateKey = base64.StdEncoding.EncodeToString(pem.EncodeToMemory(block))
+
+				err := DecryptKey(creds, ProtectWithPassphrase([]byte("secret")))
+
+				Convey("Then err should be correct", func() {
+					So(err, ShouldNotBeNil)
+					So(err.Error(), ShouldEqual, "invalid scrypt parameters: N and R would use more than 1073741824 bytes")
+				})
+			})
+
+			Convey("When I decrypt it with a key file", func() {
+
+				err := DecryptKey(creds, ProtectWithKeyFile("/nope"))
+
+				Convey("Then err should be correct", func() {
+					So(err, ShouldNotBeNil)
+					So(err.Error(), ShouldEqual, "private key has been encrypted using 'scrypt' protection")
+				})
+			})
+		})
+
+		Convey("When I encrypt the key with an empty passphrase", func() {
+
+			err := EncryptKey(creds, ProtectWithPassphrase(nil))
+
+			Convey("Then err should be correct", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "empty passphrase")
+				So(creds.CertificateKey, ShouldEqual, clearKey)
+			})
+		})
+
+		Convey("When I decrypt a clear key", func() {
+
+			err := DecryptKey(creds, ProtectWithPassphrase([]byte("secret")))
+
+			Convey("Then err should be correct", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "private key is not encrypted")
+			})
+		})
+	})
+
+	Convey("Given I have credentials and a key file", t, func() {
+
+		dir, err := ioutil.TempDir("", "appcreds")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir) // nolint: errcheck
+
+		keyFile := filepath.Join(dir, "key")
+		So(GenerateKeyFile(keyFile), ShouldBeNil)
+
+		creds := makeTestCredentials(time.Now().Add(time.Hour))
+		clearKey := creds.CertificateKey
+
+		Convey("When I encrypt and decrypt the key with the key file", func() {
+
+			err1 := EncryptKey(creds, ProtectWithKeyFile(keyFile))
+			encrypted := IsKeyEncrypted(creds)
+			err2 := DecryptKey(creds, ProtectWithKeyFile(keyFile))
+
+			Convey("Then the key should be restored", func() {
+				So(err1, ShouldBeNil)
+				So(encrypted, ShouldBeTrue)
+				So(err2, ShouldBeNil)
+				So(creds.CertificateKey, ShouldEqual, clearKey)
+			})
+		})
+
+		Convey("When I encrypt the key with a key file that is too short", func() {
+
+			So(ioutil.WriteFile(keyFile, []byte("short"), 0600), ShouldBeNil)
+
+			err := EncryptKey(creds, ProtectWithKeyFile(keyFile))
+
+			Convey("Then err should be correct", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "key file must contain at least 32 bytes")
+			})
+		})
+	})
+}
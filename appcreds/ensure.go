@@ -0,0 +1,232 @@
+package appcreds
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.aporeto.io/elemental"
+	"go.aporeto.io/gaia"
+	"go.aporeto.io/manipulate"
+)
+
+// An EnsureAction describes what Ensure had to do.
+// Actions are flags, as an appcred can be both updated and renewed.
+type EnsureAction int
+
+// Various values for EnsureAction.
+const (
+	EnsureActionCreated EnsureAction = 1 << iota
+	EnsureActionUpdated
+	EnsureActionRenewed
+
+	EnsureActionNone EnsureAction = 0
+)
+
+// Has returns true if the given action has been done.
+func (a EnsureAction) Has(action EnsureAction) bool {
+	return a&action != 0
+}
+
+func (a EnsureAction) String() string {
+
+	if a == EnsureActionNone {
+		return "none"
+	}
+
+	var actions []string
+	for _, action := range []struct {
+		flag EnsureAction
+		name string
+	}{
+		{EnsureActionCreated, "created"},
+		{EnsureActionUpdated, "updated"},
+		{EnsureActionRenewed, "renewed"},
+	} {
+		if a.Has(action.flag) {
+			actions = append(actions, action.name)
+		}
+	}
+
+	return strings.Join(actions, "+")
+}
+
+type ensureConfig struct {
+	options     []Option
+	renewBefore time.Duration
+}
+
+// An EnsureOption can be used to configure Ensure.
+type EnsureOption func(*ensureConfig)
+
+// EnsureOptionCreate sets the options of the appcred, like OptionSubnets.
+// They are the ones given to NewWithOptions when the appcred is created,
+// and the subnets are compared to the existing ones to detect a drift.
+func EnsureOptionCreate(options ...Option) EnsureOption {
+	return func(c *ensureConfig) {
+		c.options = append(c.options, options...)
+	}
+}
+
+// EnsureOptionRenewBefore configures Ensure to renew the appcred
+// if its certificate expires within the given duration.
+func EnsureOptionRenewBefore(d time.Duration) EnsureOption {
+	return func(c *ensureConfig) {
+		c.renewBefore = d
+	}
+}
+
+// Ensure makes sure an appcred with the given name exists in the
+// given namespace with the given roles and options.
+//
+// If no appcred exists with that name, it is created. If it exists but
+// its roles or subnets drifted, it is updated. If EnsureOptionRenewBefore
+// is used and the certificate of the appcred expires within the given
+// duration, it is renewed. Otherwise nothing is done. The options of the
+// appcred itself are given using EnsureOptionCreate.
+//
+// The returned appcred only contains a private key when it has been
+// created or renewed. The returned EnsureAction reports what was done.
+// If the appcred drifted and had to be renewed, it is both updated and renewed.
+func Ensure(
+	ctx context.Context,
+	m manipulate.Manipulator,
+	namespace string,
+	name string,
+	roles []string,
+	options ...EnsureOption,
+) (*gaia.AppCredential, EnsureAction, error) {
+
+	ecfg := ensureConfig{}
+	for _, opt := range options {
+		opt(&ecfg)
+	}
+
+	cfg := newConfig()
+	for _, opt := range ecfg.options {
+		opt(&cfg)
+	}
+
+	if err := cfg.validate(name, roles); err != nil {
+		return nil, EnsureActionNone, err
+	}
+
+	if cfg.parentNamespace != "" {
+		namespace = cfg.parentNamespace
+	}
+
+	existing, err := retrieveByName(ctx, m, namespace, name)
+	if err != nil {
+		return nil, EnsureActionNone, err
+	}
+
+	if existing == nil {
+		creds, err := NewWithOptions(ctx, m, namespace, name, roles, ecfg.options...)
+		if err != nil {
+			return nil, EnsureActionNone, err
+		}
+		return creds, EnsureActionCreated, nil
+	}
+
+	drifted := !sameStrings(existing.Roles, roles) || !sameStrings(existing.AuthorizedSubnets, cfg.subnets)
+	existing.Roles = roles
+	existing.AuthorizedSubnets = cfg.subnets
+
+	if ecfg.renewBefore > 0 && needsRenewal(existing, ecfg.renewBefore) {
+		creds, err := Renew(ctx, m, existing)
+		if err != nil {
+			return nil, EnsureActionNone, err
+		}
+		if drifted {
+			return creds, EnsureActionUpdated | EnsureActionRenewed, nil
+		}
+		return creds, EnsureActionRenewed, nil
+	}
+
+	if !drifted {
+		return existing, EnsureActionNone, nil
+	}
+
+	if err := m.Update(
+		manipulate.NewContext(
+			ctx,
+			manipulate.ContextOptionNamespace(namespace),
+		),
+		existing,
+	); err != nil {
+		return nil, EnsureActionNone, err
+	}
+
+	return existing, EnsureActionUpdated, nil
+}
+
+func retrieveByName(ctx context.Context, m manipulate.Manipulator, namespace string, name string) (*gaia.AppCredential, error) {
+
+	list := gaia.AppCredentialsList{}
+	if err := m.RetrieveMany(
+		manipulate.NewContext(
+			ctx,
+			manipulate.ContextOptionNamespace(namespace),
+			manipulate.ContextOptionFilter(
+				elemental.NewFilterComposer().WithKey("name").Equals(name).Done(),
+			),
+		),
+		&list,
+	); err != nil {
+		return nil, err
+	}
+
+	var found *gaia.AppCredential
+	for _, ac := range list {
+
+		if ac.Name != name {
+			continue
+		}
+
+		if found != nil {
+			return nil, fmt.Errorf("multiple appcreds named '%s' found in namespace '%s'", name, namespace)
+		}
+
+		found = ac
+	}
+
+	return found, nil
+}
+
+// needsRenewal returns true if the certificate of the given
+// appcred is missing, unreadable or expires within the given duration.
+func needsRenewal(creds *gaia.AppCredential, within time.Duration) bool {
+
+	if creds.Credentials == nil {
+		return true
+	}
+
+	cert, err := decodeCertificate(creds.Credentials.Certificate)
+	if err != nil {
+		return true
+	}
+
+	return time.Until(cert.NotAfter) < within
+}
+
+func sameStrings(a []string, b []string) bool {
+
+	if len(a) != len(b) {
+		return false
+	}
+
+	sa := append([]string{}, a...)
+	sb := append([]string{}, b...)
+	sort.Strings(sa)
+	sort.Strings(sb)
+
+	for i := range sa {
+		if sa[i] != sb[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,215 @@
+package appcreds
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.aporeto.io/elemental"
+	"go.aporeto.io/gaia"
+	"go.aporeto.io/manipulate"
+	"go.aporeto.io/manipulate/maniptest"
+)
+
+func TestEnsure(t *testing.T) {
+
+	Convey("Given I have a manipulator with no existing appcred", t, func() {
+
+		m := maniptest.NewTestManipulator()
+
+		m.MockRetrieveMany(t, func(ctx manipulate.Context, dest elemental.Identifiables) error {
+			*dest.(*gaia.AppCredentialsList) = gaia.AppCredentialsList{}
+			return nil
+		})
+
+		var created bool
+		m.MockCreate(t, func(ctx manipulate.Context, object elemental.Identifiable) error {
+			created = true
+			ac := object.(*gaia.AppCredential)
+			ac.ID = "ID"
+			ac.Credentials = gaia.NewCredential()
+			return nil
+		})
+
+		Convey("When I call Ensure", func() {
+
+			c, action, err := Ensure(context.Background(), m, "/ns", "name", []string{"@auth:role=role1"})
+
+			Convey("Then the appcred should be created", func() {
+				So(err, ShouldBeNil)
+				So(action, ShouldEqual, EnsureActionCreated)
+				So(created, ShouldBeTrue)
+				So(c.ID, ShouldEqual, "ID")
+				So(c.Credentials.CertificateKey, ShouldNotBeEmpty)
+			})
+		})
+	})
+
+	Convey("Given I have a manipulator with an existing appcred", t, func() {
+
+		m := maniptest.NewTestManipulator()
+
+		existing := gaia.NewAppCredential()
+		existing.ID = "ID"
+		existing.Name = "name"
+		existing.Namespace = "/ns"
+		existing.Roles = []string{"@auth:role=role2", "@auth:role=role1"}
+		existing.AuthorizedSubnets = []string{"10.0.0.0/8"}
+		existing.Credentials = makeTestCredentials(time.Now().Add(48 * time.Hour))
+
+		other := gaia.NewAppCredential()
+		other.Name = "other"
+
+		m.MockRetrieveMany(t, func(ctx manipulate.Context, dest elemental.Identifiables) error {
+			if ctx.Namespace() != "/ns" {
+				panic("expected ns to be /ns")
+			}
+			*dest.(*gaia.AppCredentialsList) = gaia.AppCredentialsList{other, existing}
+			return nil
+		})
+
+		var updated *gaia.AppCredential
+		m.MockUpdate(t, func(ctx manipulate.Context, object elemental.Identifiable) error {
+			updated = object.(*gaia.AppCredential)
+			return nil
+		})
+
+		Convey("When I call Ensure with the same configuration", func() {
+
+			c, action, err := Ensure(
+				context.Background(), m, "/ns", "name",
+				[]string{"@auth:role=role1", "@auth:role=role2"},
+				EnsureOptionCreate(OptionSubnets([]string{"10.0.0.0/8"})),
+				EnsureOptionRenewBefore(time.Hour),
+			)
+
+			Convey("Then nothing should be done", func() {
+				So(err, ShouldBeNil)
+				So(action, ShouldEqual, EnsureActionNone)
+				So(updated, ShouldBeNil)
+				So(c.ID, ShouldEqual, "ID")
+			})
+		})
+
+		Convey("When I call Ensure with different roles", func() {
+
+			c, action, err := Ensure(
+				context.Background(), m, "/ns", "name",
+				[]string{"@auth:role=role3"},
+				EnsureOptionCreate(OptionSubnets([]string{"10.0.0.0/8"})),
+			)
+
+			Convey("Then the appcred should be updated", func() {
+				So(err, ShouldBeNil)
+				So(action, ShouldEqual, EnsureActionUpdated)
+				So(updated, ShouldEqual, existing)
+				So(c.Roles, ShouldResemble, []string{"@auth:role=role3"})
+				So(c.CSR, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When I call Ensure and the certificate expires soon", func() {
+
+			c, action, err := Ensure(
+				context.Background(), m, "/ns", "name",
+				[]string{"@auth:role=role1", "@auth:role=role2"},
+				EnsureOptionCreate(OptionSubnets([]string{"10.0.0.0/8"})),
+				EnsureOptionRenewBefore(72*time.Hour),
+			)
+
+			Convey("Then the appcred should be renewed", func() {
+				So(err, ShouldBeNil)
+				So(action, ShouldEqual, EnsureActionRenewed)
+				So(action.String(), ShouldEqual, "renewed")
+				So(updated.ID, ShouldEqual, "ID")
+				So(c.CSR, ShouldNotBeEmpty)
+				So(c.Credentials.CertificateKey, ShouldNotBeEmpty)
+			})
+		})
+
+		Convey("When I call Ensure with different roles and the certificate expires soon", func() {
+
+			c, action, err := Ensure(
+				context.Background(), m, "/ns", "name",
+				[]string{"@auth:role=role3"},
+				EnsureOptionCreate(OptionSubnets([]string{"10.0.0.0/8"})),
+				EnsureOptionRenewBefore(72*time.Hour),
+			)
+
+			Convey("Then the appcred should be both updated and renewed", func() {
+				So(err, ShouldBeNil)
+				So(action.Has(EnsureActionUpdated), ShouldBeTrue)
+				So(action.Has(EnsureActionRenewed), ShouldBeTrue)
+				So(action.Has(EnsureActionCreated), ShouldBeFalse)
+				So(action.String(), ShouldEqual, "updated+renewed")
+				So(updated.Roles, ShouldResemble, []string{"@auth:role=role3"})
+				So(c.Roles, ShouldResemble, []string{"@auth:role=role3"})
+				So(c.Credentials.CertificateKey, ShouldNotBeEmpty)
+			})
+		})
+
+		Convey("When I call Ensure and the update fails", func() {
+
+			m.MockUpdate(t, func(ctx manipulate.Context, object elemental.Identifiable) error {
+				return fmt.Errorf("boom")
+			})
+
+			c, action, err := Ensure(context.Background(), m, "/ns", "name", []string{"@auth:role=role3"})
+
+			Convey("Then err should be correct", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "boom")
+				So(action, ShouldEqual, EnsureActionNone)
+				So(c, ShouldBeNil)
+			})
+		})
+	})
+
+	Convey("Given I have a manipulator with duplicate appcreds", t, func() {
+
+		m := maniptest.NewTestManipulator()
+
+		m.MockRetrieveMany(t, func(ctx manipulate.Context, dest elemental.Identifiables) error {
+			a := gaia.NewAppCredential()
+			a.Name = "name"
+			b := gaia.NewAppCredential()
+			b.Name = "name"
+			*dest.(*gaia.AppCredentialsList) = gaia.AppCredentialsList{a, b}
+			return nil
+		})
+
+		Convey("When I call Ensure", func() {
+
+			c, action, err := Ensure(context.Background(), m, "/ns", "name", []string{"@auth:role=role1"})
+
+			Convey("Then err should be correct", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "multiple appcreds named 'name' found in namespace '/ns'")
+				So(action, ShouldEqual, EnsureActionNone)
+				So(c, ShouldBeNil)
+			})
+		})
+	})
+
+	Convey("Given I have a manipulator that fails to retrieve", t, func() {
+
+		m := maniptest.NewTestManipulator()
+
+		m.MockRetrieveMany(t, func(ctx manipulate.Context, dest elemental.Identifiables) error {
+			return fmt.Errorf("boom")
+		})
+
+		Convey("When I call Ensure", func() {
+
+			c, _, err := Ensure(context.Background(), m, "/ns", "name", []string{"@auth:role=role1"})
+
+			Convey("Then err should be correct", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "boom")
+				So(c, ShouldBeNil)
+			})
+		})
+	})
+}
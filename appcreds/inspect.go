@@ -0,0 +1,176 @@
+package appcreds
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.aporeto.io/gaia"
+)
+
+// A Report contains information about the
+// certificate of an appcred.
+type Report struct {
+	SerialNumber          string
+	Subject               string
+	Issuer                string
+	NotBefore             time.Time
+	NotAfter              time.Time
+	KeyType               string
+	KeyMatchesCertificate bool
+	RemainingValidity     time.Duration
+}
+
+// Inspect returns a *Report about the certificate of the given appcred.
+// If the appcred has no private key, KeyMatchesCertificate is false.
+func Inspect(creds *gaia.AppCredential) (*Report, error) {
+
+	if creds.Credentials == nil {
+		return nil, errors.New("appcred has no credentials")
+	}
+
+	cert, err := decodeCertificate(creds.Credentials.Certificate)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		SerialNumber: cert.SerialNumber.String(),
+		Subject:      cert.Subject.String(),
+		Issuer:       cert.Issuer.String(),
+		NotBefore:    cert.NotBefore,
+		NotAfter:     cert.NotAfter,
+		KeyType:      keyType(cert.PublicKey),
+	}
+
+	if remaining := time.Until(cert.NotAfter); remaining > 0 {
+		report.RemainingValidity = remaining
+	}
+
+	if creds.Credentials.CertificateKey != "" {
+
+		key, err := decodePrivateKey(creds.Credentials.CertificateKey)
+		if err != nil {
+			return nil, err
+		}
+
+		report.KeyMatchesCertificate = keyMatches(key, cert.PublicKey)
+	}
+
+	return report, nil
+}
+
+// Verify verifies the certificate of the given appcred
+// has been issued by the certificate authority of the appcred
+// and is currently valid.
+func Verify(creds *gaia.AppCredential) error {
+
+	if creds.Credentials == nil {
+		return errors.New("appcred has no credentials")
+	}
+
+	cert, err := decodeCertificate(creds.Credentials.Certificate)
+	if err != nil {
+		return err
+	}
+
+	pool, err := decodeCertificateAuthority(creds.Credentials.CertificateAuthority)
+	if err != nil {
+		return err
+	}
+
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:     pool,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return fmt.Errorf("invalid certificate: %w", err)
+	}
+
+	return nil
+}
+
+// decodeCertificateAuthority decodes a certificate authority
+// as stored in the credentials of an appcred.
+func decodeCertificateAuthority(data string) (*x509.CertPool, error) {
+
+	caData, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode certificate authority: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caData) {
+		return nil, errors.New("unable to decode certificate authority: no certificate found")
+	}
+
+	return pool, nil
+}
+
+// decodePrivateKey decodes a private key as
+// stored in the credentials of an appcred.
+func decodePrivateKey(data string) (crypto.PrivateKey, error) {
+
+	pemData, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode private key: %w", err)
+	}
+
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, errors.New("unable to decode private key: no pem block found")
+	}
+
+	if block.Type == encryptedKeyBlockType {
+		return nil, errors.New("unable to decode private key: key is encrypted")
+	}
+
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	return nil, errors.New("unable to decode private key: unsupported key format")
+}
+
+func keyMatches(key crypto.PrivateKey, pub crypto.PublicKey) bool {
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return false
+	}
+
+	kpub, ok := signer.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !ok {
+		return false
+	}
+
+	return kpub.Equal(pub)
+}
+
+func keyType(pub crypto.PublicKey) string {
+
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		return fmt.Sprintf("ECDSA %s", k.Curve.Params().Name)
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA %d", k.N.BitLen())
+	case ed25519.PublicKey:
+		return "Ed25519"
+	default:
+		return "unknown"
+	}
+}
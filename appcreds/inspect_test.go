@@ -0,0 +1,213 @@
+package appcreds
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.aporeto.io/gaia"
+)
+
+// makeTestCredentials returns credentials signed
+// by a newly generated certificate authority.
+func makeTestCredentials(notAfter time.Time) *gaia.Credential {
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * 365 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, caKey.Public(), caKey)
+	if err != nil {
+		panic(err)
+	}
+
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		panic(err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "app"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, caCert, key.Public(), caKey)
+	if err != nil {
+		panic(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		panic(err)
+	}
+
+	c := gaia.NewCredential()
+	c.Certificate = base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	c.CertificateKey = base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+	c.CertificateAuthority = base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}))
+
+	return c
+}
+
+func TestInspect(t *testing.T) {
+
+	Convey("Given I have an appcred", t, func() {
+
+		ac := gaia.NewAppCredential()
+		ac.Credentials = makeTestCredentials(time.Now().Add(48 * time.Hour))
+
+		Convey("When I call Inspect", func() {
+
+			report, err := Inspect(ac)
+
+			Convey("Then err should be nil", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("Then the report should be correct", func() {
+				So(report.SerialNumber, ShouldEqual, "42")
+				So(report.Subject, ShouldEqual, "CN=app")
+				So(report.Issuer, ShouldEqual, "CN=ca")
+				So(report.KeyType, ShouldEqual, "ECDSA P-256")
+				So(report.KeyMatchesCertificate, ShouldBeTrue)
+				So(report.RemainingValidity, ShouldBeGreaterThan, 47*time.Hour)
+				So(report.NotAfter.After(report.NotBefore), ShouldBeTrue)
+			})
+		})
+
+		Convey("When I call Inspect with the key of another appcred", func() {
+
+			ac.Credentials.CertificateKey = makeTestCredentials(time.Now().Add(time.Hour)).CertificateKey
+
+			report, err := Inspect(ac)
+
+			Convey("Then the key should not match", func() {
+				So(err, ShouldBeNil)
+				So(report.KeyMatchesCertificate, ShouldBeFalse)
+			})
+		})
+
+		Convey("When I call Inspect without key", func() {
+
+			ac.Credentials.CertificateKey = ""
+
+			report, err := Inspect(ac)
+
+			Convey("Then the key should not match", func() {
+				So(err, ShouldBeNil)
+				So(report.KeyMatchesCertificate, ShouldBeFalse)
+			})
+		})
+
+		Convey("When I call Inspect with an invalid certificate", func() {
+
+			ac.Credentials.Certificate = "not base64"
+
+			report, err := Inspect(ac)
+
+			Convey("Then err should not be nil", func() {
+				So(err, ShouldNotBeNil)
+				So(report, ShouldBeNil)
+			})
+		})
+	})
+
+	Convey("Given I have an expired appcred", t, func() {
+
+		ac := gaia.NewAppCredential()
+		ac.Credentials = makeTestCredentials(time.Now().Add(-time.Minute))
+
+		Convey("When I call Inspect", func() {
+
+			report, err := Inspect(ac)
+
+			Convey("Then there should be no remaining validity", func() {
+				So(err, ShouldBeNil)
+				So(report.RemainingValidity, ShouldEqual, 0)
+			})
+		})
+	})
+}
+
+func TestVerify(t *testing.T) {
+
+	Convey("Given I have a valid appcred", t, func() {
+
+		ac := gaia.NewAppCredential()
+		ac.Credentials = makeTestCredentials(time.Now().Add(time.Hour))
+
+		Convey("When I call Verify", func() {
+
+			err := Verify(ac)
+
+			Convey("Then err should be nil", func() {
+				So(err, ShouldBeNil)
+			})
+		})
+
+		Convey("When I call Verify with another certificate authority", func() {
+
+			ac.Credentials.CertificateAuthority = makeTestCredentials(time.Now().Add(time.Hour)).CertificateAuthority
+
+			err := Verify(ac)
+
+			Convey("Then err should not be nil", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When I call Verify without certificate authority", func() {
+
+			ac.Credentials.CertificateAuthority = ""
+
+			err := Verify(ac)
+
+			Convey("Then err should be correct", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "unable to decode certificate authority: no certificate found")
+			})
+		})
+	})
+
+	Convey("Given I have an expired appcred", t, func() {
+
+		ac := gaia.NewAppCredential()
+		ac.Credentials = makeTestCredentials(time.Now().Add(-time.Minute))
+
+		Convey("When I call Verify", func() {
+
+			err := Verify(ac)
+
+			Convey("Then err should not be nil", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
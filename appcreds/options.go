@@ -3,8 +3,14 @@ package appcreds
 import "time"
 
 type config struct {
-	subnets     []string
-	maxValidity time.Duration
+	subnets         []string
+	maxValidity     time.Duration
+	description     string
+	metadata        []string
+	protected       bool
+	annotations     map[string][]string
+	associatedTags  []string
+	parentNamespace string
 }
 
 func newConfig() config {
@@ -29,3 +35,46 @@ func OptionMaxValidity(max time.Duration) Option {
 		c.maxValidity = max
 	}
 }
+
+// OptionDescription sets the description of the appcred.
+func OptionDescription(description string) Option {
+	return func(c *config) {
+		c.description = description
+	}
+}
+
+// OptionMetadata sets the metadata tags of the appcred.
+func OptionMetadata(metadata []string) Option {
+	return func(c *config) {
+		c.metadata = metadata
+	}
+}
+
+// OptionProtected sets the protected flag of the appcred.
+func OptionProtected(protected bool) Option {
+	return func(c *config) {
+		c.protected = protected
+	}
+}
+
+// OptionAnnotations sets the annotations of the appcred.
+func OptionAnnotations(annotations map[string][]string) Option {
+	return func(c *config) {
+		c.annotations = annotations
+	}
+}
+
+// OptionAssociatedTags sets the associated tags of the appcred.
+func OptionAssociatedTags(tags []string) Option {
+	return func(c *config) {
+		c.associatedTags = tags
+	}
+}
+
+// OptionParentNamespace overrides the namespace
+// in which the appcred will be created.
+func OptionParentNamespace(namespace string) Option {
+	return func(c *config) {
+		c.parentNamespace = namespace
+	}
+}
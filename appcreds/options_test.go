@@ -26,4 +26,40 @@ func TestOptions(t *testing.T) {
 		OptionMaxValidity(3 * time.Minute)(&cfg)
 		So(cfg.maxValidity, ShouldEqual, 3*time.Minute)
 	})
+
+	Convey("calling OptionDescription should work", t, func() {
+		cfg := newConfig()
+		OptionDescription("desc")(&cfg)
+		So(cfg.description, ShouldEqual, "desc")
+	})
+
+	Convey("calling OptionMetadata should work", t, func() {
+		cfg := newConfig()
+		OptionMetadata([]string{"a=b"})(&cfg)
+		So(cfg.metadata, ShouldResemble, []string{"a=b"})
+	})
+
+	Convey("calling OptionProtected should work", t, func() {
+		cfg := newConfig()
+		OptionProtected(true)(&cfg)
+		So(cfg.protected, ShouldBeTrue)
+	})
+
+	Convey("calling OptionAnnotations should work", t, func() {
+		cfg := newConfig()
+		OptionAnnotations(map[string][]string{"a": {"b"}})(&cfg)
+		So(cfg.annotations, ShouldResemble, map[string][]string{"a": {"b"}})
+	})
+
+	Convey("calling OptionAssociatedTags should work", t, func() {
+		cfg := newConfig()
+		OptionAssociatedTags([]string{"a=b"})(&cfg)
+		So(cfg.associatedTags, ShouldResemble, []string{"a=b"})
+	})
+
+	Convey("calling OptionParentNamespace should work", t, func() {
+		cfg := newConfig()
+		OptionParentNamespace("/other")(&cfg)
+		So(cfg.parentNamespace, ShouldEqual, "/other")
+	})
 }
@@ -0,0 +1,140 @@
+package appcreds
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.aporeto.io/gaia"
+	"go.aporeto.io/manipulate"
+)
+
+// Delete deletes the given appcred using the given manipulator.
+// Once the appcred is deleted, the given local files written
+// for it (credentials, keys, certificates...) are wiped.
+func Delete(ctx context.Context, m manipulate.Manipulator, creds *gaia.AppCredential, files ...string) error {
+
+	if err := m.Delete(
+		manipulate.NewContext(
+			ctx,
+			manipulate.ContextOptionNamespace(creds.Namespace),
+		),
+		creds,
+	); err != nil {
+		return err
+	}
+
+	return wipeFiles(files...)
+}
+
+// Revoke deletes the appcred with the given name in the given
+// namespace, then wipes the given local files written for it.
+func Revoke(ctx context.Context, m manipulate.Manipulator, namespace string, name string, files ...string) error {
+
+	creds, err := retrieveByName(ctx, m, namespace, name)
+	if err != nil {
+		return err
+	}
+
+	if creds == nil {
+		return fmt.Errorf("no appcred named '%s' found in namespace '%s'", name, namespace)
+	}
+
+	if creds.Namespace == "" {
+		creds.Namespace = namespace
+	}
+
+	return Delete(ctx, m, creds, files...)
+}
+
+// A RotationResult holds the outcome of the rotation
+// of a single appcred.
+type RotationResult struct {
+	Credential *gaia.AppCredential
+	Err        error
+}
+
+// Rotate renews every appcred in the given namespace for which
+// the given filter returns true. If filter is nil, all appcreds
+// are renewed. A failure to renew one appcred does not stop the
+// rotation of the others: the outcome of each rotation is returned
+// as a RotationResult. The error is only set if the appcreds
+// could not be listed.
+func Rotate(ctx context.Context, m manipulate.Manipulator, namespace string, filter func(*gaia.AppCredential) bool) ([]RotationResult, error) {
+
+	list := gaia.AppCredentialsList{}
+	if err := m.RetrieveMany(
+		manipulate.NewContext(
+			ctx,
+			manipulate.ContextOptionNamespace(namespace),
+		),
+		&list,
+	); err != nil {
+		return nil, err
+	}
+
+	results := make([]RotationResult, 0, len(list))
+	for _, creds := range list {
+
+		if filter != nil && !filter(creds) {
+			continue
+		}
+
+		renewed, err := Renew(ctx, m, creds)
+		if err != nil {
+			results = append(results, RotationResult{Credential: creds, Err: err})
+			continue
+		}
+
+		results = append(results, RotationResult{Credential: renewed})
+	}
+
+	return results, nil
+}
+
+// wipeFiles overwrites the content of the given
+// files with zeros then removes them. Files that
+// do not exist are ignored.
+func wipeFiles(files ...string) error {
+
+	for _, path := range files {
+
+		if err := wipeFile(path); err != nil {
+			return fmt.Errorf("unable to wipe '%s': %w", path, err)
+		}
+	}
+
+	return nil
+}
+
+func wipeFile(path string) error {
+
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY, 0) // #nosec
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(make([]byte, info.Size())); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(path)
+}
@@ -0,0 +1,209 @@
+package appcreds
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.aporeto.io/elemental"
+	"go.aporeto.io/gaia"
+	"go.aporeto.io/manipulate"
+	"go.aporeto.io/manipulate/maniptest"
+)
+
+func TestDelete(t *testing.T) {
+
+	Convey("Given I have a manipulator and a local file", t, func() {
+
+		dir, err := ioutil.TempDir("", "appcreds")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir) // nolint: errcheck
+
+		path := filepath.Join(dir, "creds.json")
+		So(ioutil.WriteFile(path, []byte("secret"), 0600), ShouldBeNil)
+
+		m := maniptest.NewTestManipulator()
+
+		ac := gaia.NewAppCredential()
+		ac.ID = "ID"
+		ac.Namespace = "/ns"
+
+		var deleted elemental.Identifiable
+		m.MockDelete(t, func(ctx manipulate.Context, object elemental.Identifiable) error {
+			if ctx.Namespace() != "/ns" {
+				panic("expected ns to be /ns")
+			}
+			deleted = object
+			return nil
+		})
+
+		Convey("When I call Delete", func() {
+
+			err := Delete(context.Background(), m, ac, path, filepath.Join(dir, "missing"))
+
+			Convey("Then err should be nil", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("Then the appcred should be deleted", func() {
+				So(deleted, ShouldEqual, ac)
+			})
+
+			Convey("Then the file should be removed", func() {
+				_, err := os.Stat(path)
+				So(os.IsNotExist(err), ShouldBeTrue)
+			})
+		})
+
+		Convey("When I call Delete and the manipulator fails", func() {
+
+			m.MockDelete(t, func(ctx manipulate.Context, object elemental.Identifiable) error {
+				return fmt.Errorf("boom")
+			})
+
+			err := Delete(context.Background(), m, ac, path)
+
+			Convey("Then err should be correct", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "boom")
+			})
+
+			Convey("Then the file should be left untouched", func() {
+				data, err := ioutil.ReadFile(path)
+				So(err, ShouldBeNil)
+				So(string(data), ShouldEqual, "secret")
+			})
+		})
+	})
+}
+
+func TestRevoke(t *testing.T) {
+
+	Convey("Given I have a manipulator", t, func() {
+
+		m := maniptest.NewTestManipulator()
+
+		m.MockRetrieveMany(t, func(ctx manipulate.Context, dest elemental.Identifiables) error {
+			ac := gaia.NewAppCredential()
+			ac.ID = "ID"
+			ac.Name = "name"
+			*dest.(*gaia.AppCredentialsList) = gaia.AppCredentialsList{ac}
+			return nil
+		})
+
+		var deleted string
+		m.MockDelete(t, func(ctx manipulate.Context, object elemental.Identifiable) error {
+			if ctx.Namespace() != "/ns" {
+				panic("expected ns to be /ns")
+			}
+			deleted = object.Identifier()
+			return nil
+		})
+
+		Convey("When I call Revoke on an existing appcred", func() {
+
+			err := Revoke(context.Background(), m, "/ns", "name")
+
+			Convey("Then the appcred should be deleted", func() {
+				So(err, ShouldBeNil)
+				So(deleted, ShouldEqual, "ID")
+			})
+		})
+
+		Convey("When I call Revoke on a missing appcred", func() {
+
+			err := Revoke(context.Background(), m, "/ns", "missing")
+
+			Convey("Then err should be correct", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "no appcred named 'missing' found in namespace '/ns'")
+				So(deleted, ShouldBeEmpty)
+			})
+		})
+	})
+}
+
+func TestRotate(t *testing.T) {
+
+	Convey("Given I have a manipulator with some appcreds", t, func() {
+
+		m := maniptest.NewTestManipulator()
+
+		m.MockRetrieveMany(t, func(ctx manipulate.Context, dest elemental.Identifiables) error {
+			var list gaia.AppCredentialsList
+			for _, name := range []string{"a", "b", "c"} {
+				ac := gaia.NewAppCredential()
+				ac.ID = name
+				ac.Name = name
+				ac.Namespace = "/ns"
+				list = append(list, ac)
+			}
+			*dest.(*gaia.AppCredentialsList) = list
+			return nil
+		})
+
+		m.MockUpdate(t, func(ctx manipulate.Context, object elemental.Identifiable) error {
+			ac := object.(*gaia.AppCredential)
+			if ac.Name == "b" {
+				return fmt.Errorf("boom")
+			}
+			ac.Credentials = gaia.NewCredential()
+			return nil
+		})
+
+		Convey("When I call Rotate with a filter", func() {
+
+			results, err := Rotate(context.Background(), m, "/ns", func(ac *gaia.AppCredential) bool {
+				return ac.Name != "c"
+			})
+
+			Convey("Then err should be nil", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("Then the results should be correct", func() {
+				So(len(results), ShouldEqual, 2)
+				So(results[0].Err, ShouldBeNil)
+				So(results[0].Credential.Name, ShouldEqual, "a")
+				So(results[0].Credential.Credentials.CertificateKey, ShouldNotBeEmpty)
+				So(results[1].Err, ShouldNotBeNil)
+				So(results[1].Err.Error(), ShouldEqual, "boom")
+				So(results[1].Credential.Name, ShouldEqual, "b")
+			})
+		})
+
+		Convey("When I call Rotate without filter", func() {
+
+			results, err := Rotate(context.Background(), m, "/ns", nil)
+
+			Convey("Then all appcreds should be rotated", func() {
+				So(err, ShouldBeNil)
+				So(len(results), ShouldEqual, 3)
+			})
+		})
+	})
+
+	Convey("Given I have a manipulator that fails to list", t, func() {
+
+		m := maniptest.NewTestManipulator()
+
+		m.MockRetrieveMany(t, func(ctx manipulate.Context, dest elemental.Identifiables) error {
+			return fmt.Errorf("boom")
+		})
+
+		Convey("When I call Rotate", func() {
+
+			results, err := Rotate(context.Background(), m, "/ns", nil)
+
+			Convey("Then err should be correct", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "boom")
+				So(results, ShouldBeNil)
+			})
+		})
+	})
+}
@@ -0,0 +1,45 @@
+package appcreds
+
+import (
+	"crypto/tls"
+	"errors"
+
+	"go.aporeto.io/gaia"
+)
+
+// TLSConfig returns a *tls.Config using the certificate and key of the
+// given credentials as client certificate and their certificate authority
+// as root CAs. It returns an error if the key does not match the certificate.
+func TLSConfig(creds *gaia.Credential) (*tls.Config, error) {
+
+	cert, err := decodeCertificate(creds.Certificate)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := decodePrivateKey(creds.CertificateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if !keyMatches(key, cert.PublicKey) {
+		return nil, errors.New("private key does not match certificate")
+	}
+
+	pool, err := decodeCertificateAuthority(creds.CertificateAuthority)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{
+			{
+				Certificate: [][]byte{cert.Raw},
+				PrivateKey:  key,
+				Leaf:        cert,
+			},
+		},
+		RootCAs:    pool,
+		MinVersion: tls.VersionTLS12,
+	}, nil
+}
@@ -0,0 +1,54 @@
+package appcreds
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTLSConfig(t *testing.T) {
+
+	Convey("Given I have valid credentials", t, func() {
+
+		creds := makeTestCredentials(time.Now().Add(time.Hour))
+
+		Convey("When I call TLSConfig", func() {
+
+			cfg, err := TLSConfig(creds)
+
+			Convey("Then the config should be correct", func() {
+				So(err, ShouldBeNil)
+				So(len(cfg.Certificates), ShouldEqual, 1)
+				So(cfg.Certificates[0].Leaf.Subject.CommonName, ShouldEqual, "app")
+				So(cfg.RootCAs, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When I call TLSConfig with a key that does not match", func() {
+
+			creds.CertificateKey = makeTestCredentials(time.Now().Add(time.Hour)).CertificateKey
+
+			cfg, err := TLSConfig(creds)
+
+			Convey("Then err should be correct", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "private key does not match certificate")
+				So(cfg, ShouldBeNil)
+			})
+		})
+
+		Convey("When I call TLSConfig without key", func() {
+
+			creds.CertificateKey = ""
+
+			cfg, err := TLSConfig(creds)
+
+			Convey("Then err should be correct", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "unable to decode private key: no pem block found")
+				So(cfg, ShouldBeNil)
+			})
+		})
+	})
+}
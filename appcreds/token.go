@@ -0,0 +1,154 @@
+package appcreds
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.aporeto.io/addedeffect/tokenutils"
+	"go.aporeto.io/gaia"
+	"go.aporeto.io/manipulate"
+)
+
+type tokenConfig struct {
+	validity    time.Duration
+	audience    []string
+	quota       int
+	renewBefore float64
+}
+
+func newTokenConfig() tokenConfig {
+	return tokenConfig{
+		validity:    time.Hour,
+		renewBefore: 0.1,
+	}
+}
+
+// A TokenOption can be used to configure a TokenSource.
+type TokenOption func(*tokenConfig)
+
+// TokenOptionValidity sets the validity requested for the tokens.
+// It is capped to the max issued token validity of the appcred.
+// Default is 1h.
+func TokenOptionValidity(validity time.Duration) TokenOption {
+	return func(c *tokenConfig) {
+		c.validity = validity
+	}
+}
+
+// TokenOptionAudience sets the audience of the tokens.
+func TokenOptionAudience(audience ...string) TokenOption {
+	return func(c *tokenConfig) {
+		c.audience = audience
+	}
+}
+
+// TokenOptionQuota sets the number of times the tokens can be used.
+func TokenOptionQuota(quota int) TokenOption {
+	return func(c *tokenConfig) {
+		c.quota = quota
+	}
+}
+
+// TokenOptionRenewBefore sets the fraction of the validity of a token
+// remaining when a new token is issued. It must be in ]0, 1[.
+// Default is 0.1.
+func TokenOptionRenewBefore(fraction float64) TokenOption {
+	return func(c *tokenConfig) {
+		c.renewBefore = fraction
+	}
+}
+
+// A TokenSource issues tokens using an appcred and caches
+// them until they are about to expire.
+// It is safe for concurrent use.
+type TokenSource struct {
+	m   manipulate.Manipulator
+	cfg tokenConfig
+
+	token   string
+	renewAt time.Time
+	now     func() time.Time
+
+	lock sync.Mutex
+}
+
+// NewTokenSource returns a new *TokenSource issuing tokens for the given appcred.
+// The given manipulator must authenticate using the certificate of the appcred,
+// for instance with a *tls.Config returned by TLSConfig.
+func NewTokenSource(creds *gaia.AppCredential, m manipulate.Manipulator, options ...TokenOption) (*TokenSource, error) {
+
+	cfg := newTokenConfig()
+	for _, opt := range options {
+		opt(&cfg)
+	}
+
+	if cfg.validity <= 0 {
+		return nil, fmt.Errorf("invalid token validity '%s': must be positive", cfg.validity)
+	}
+
+	if cfg.renewBefore <= 0 || cfg.renewBefore >= 1 {
+		return nil, fmt.Errorf("invalid token renew fraction '%f': must be in ]0, 1[", cfg.renewBefore)
+	}
+
+	if creds.MaxIssuedTokenValidity != "" {
+
+		max, err := time.ParseDuration(creds.MaxIssuedTokenValidity)
+		if err != nil {
+			return nil, fmt.Errorf("invalid appcred max issued token validity: %w", err)
+		}
+
+		if max > 0 && cfg.validity > max {
+			cfg.validity = max
+		}
+	}
+
+	return &TokenSource{
+		m:   m,
+		cfg: cfg,
+		now: time.Now,
+	}, nil
+}
+
+// Token returns the cached token if it is not about
+// to expire or issues a new one.
+func (s *TokenSource) Token(ctx context.Context) (string, error) {
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.token != "" && s.now().Before(s.renewAt) {
+		return s.token, nil
+	}
+
+	issue := gaia.NewIssue()
+	issue.Realm = gaia.IssueRealmCertificate
+	issue.Validity = s.cfg.validity.String()
+	issue.Audience = strings.Join(s.cfg.audience, ",")
+	issue.Quota = s.cfg.quota
+
+	if err := s.m.Create(manipulate.NewContext(ctx), issue); err != nil {
+		return "", err
+	}
+
+	if issue.Token == "" {
+		return "", errors.New("no token returned by the api")
+	}
+
+	issuedAt := s.now()
+	expiresAt := issuedAt.Add(s.cfg.validity)
+
+	if claims, err := tokenutils.UnsecureClaimsMap(issue.Token); err == nil {
+		if exp, ok := claims["exp"].(float64); ok {
+			expiresAt = time.Unix(int64(exp), 0)
+		}
+	}
+
+	s.token = issue.Token
+	s.renewAt = expiresAt.Add(-time.Duration(float64(expiresAt.Sub(issuedAt)) * s.cfg.renewBefore))
+
+	return s.token, nil
+}
@@ -0,0 +1,188 @@
+package appcreds
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.aporeto.io/elemental"
+	"go.aporeto.io/gaia"
+	"go.aporeto.io/manipulate"
+	"go.aporeto.io/manipulate/maniptest"
+)
+
+func makeTestToken(exp time.Time) string {
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"exp": exp.Unix()}).SignedString([]byte("secret"))
+	if err != nil {
+		panic(err)
+	}
+
+	return token
+}
+
+func TestNewTokenSource(t *testing.T) {
+
+	Convey("Given I have an appcred with a max issued token validity", t, func() {
+
+		ac := gaia.NewAppCredential()
+		ac.MaxIssuedTokenValidity = "30m"
+
+		Convey("When I call NewTokenSource with a longer validity", func() {
+
+			s, err := NewTokenSource(ac, maniptest.NewTestManipulator(), TokenOptionValidity(time.Hour))
+
+			Convey("Then the validity should be capped", func() {
+				So(err, ShouldBeNil)
+				So(s.cfg.validity, ShouldEqual, 30*time.Minute)
+			})
+		})
+
+		Convey("When I call NewTokenSource with a shorter validity", func() {
+
+			s, err := NewTokenSource(ac, maniptest.NewTestManipulator(), TokenOptionValidity(time.Minute))
+
+			Convey("Then the validity should be kept", func() {
+				So(err, ShouldBeNil)
+				So(s.cfg.validity, ShouldEqual, time.Minute)
+			})
+		})
+
+		Convey("When I call NewTokenSource with an invalid validity", func() {
+
+			s, err := NewTokenSource(ac, maniptest.NewTestManipulator(), TokenOptionValidity(0))
+
+			Convey("Then err should be correct", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "invalid token validity '0s': must be positive")
+				So(s, ShouldBeNil)
+			})
+		})
+
+		Convey("When I call NewTokenSource with an invalid renew fraction", func() {
+
+			s, err := NewTokenSource(ac, maniptest.NewTestManipulator(), TokenOptionRenewBefore(1))
+
+			Convey("Then err should be correct", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "invalid token renew fraction '1.000000': must be in ]0, 1[")
+				So(s, ShouldBeNil)
+			})
+		})
+	})
+
+	Convey("Given I have an appcred with an invalid max issued token validity", t, func() {
+
+		ac := gaia.NewAppCredential()
+		ac.MaxIssuedTokenValidity = "nope"
+
+		Convey("When I call NewTokenSource", func() {
+
+			s, err := NewTokenSource(ac, maniptest.NewTestManipulator())
+
+			Convey("Then err should not be nil", func() {
+				So(err, ShouldNotBeNil)
+				So(s, ShouldBeNil)
+			})
+		})
+	})
+}
+
+func TestTokenSource_Token(t *testing.T) {
+
+	Convey("Given I have a token source", t, func() {
+
+		now := time.Now()
+		m := maniptest.NewTestManipulator()
+
+		var calls int
+		var lastIssue *gaia.Issue
+		m.MockCreate(t, func(ctx manipulate.Context, object elemental.Identifiable) error {
+			calls++
+			lastIssue = object.(*gaia.Issue)
+			lastIssue.Token = makeTestToken(now.Add(10 * time.Minute))
+			return nil
+		})
+
+		ac := gaia.NewAppCredential()
+		s, err := NewTokenSource(
+			ac, m,
+			TokenOptionValidity(10*time.Minute),
+			TokenOptionAudience("a", "b"),
+			TokenOptionQuota(2),
+		)
+		So(err, ShouldBeNil)
+		s.now = func() time.Time { return now }
+
+		Convey("When I call Token", func() {
+
+			token, err := s.Token(context.Background())
+
+			Convey("Then the token should be issued", func() {
+				So(err, ShouldBeNil)
+				So(token, ShouldNotBeEmpty)
+				So(calls, ShouldEqual, 1)
+				So(lastIssue.Realm, ShouldEqual, gaia.IssueRealmCertificate)
+				So(lastIssue.Validity, ShouldEqual, "10m0s")
+				So(lastIssue.Audience, ShouldEqual, "a,b")
+				So(lastIssue.Quota, ShouldEqual, 2)
+			})
+
+			Convey("When I call Token again before it is about to expire", func() {
+
+				now = now.Add(8 * time.Minute)
+				token2, err := s.Token(context.Background())
+
+				Convey("Then the cached token should be returned", func() {
+					So(err, ShouldBeNil)
+					So(token2, ShouldEqual, token)
+					So(calls, ShouldEqual, 1)
+				})
+			})
+
+			Convey("When I call Token again when it is about to expire", func() {
+
+				now = now.Add(9*time.Minute + 30*time.Second)
+				_, err := s.Token(context.Background())
+
+				Convey("Then a new token should be issued", func() {
+					So(err, ShouldBeNil)
+					So(calls, ShouldEqual, 2)
+				})
+			})
+		})
+
+		Convey("When I call Token and the api fails", func() {
+
+			m.MockCreate(t, func(ctx manipulate.Context, object elemental.Identifiable) error {
+				return fmt.Errorf("boom")
+			})
+
+			token, err := s.Token(context.Background())
+
+			Convey("Then err should be correct", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "boom")
+				So(token, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When I call Token and the api returns no token", func() {
+
+			m.MockCreate(t, func(ctx manipulate.Context, object elemental.Identifiable) error {
+				return nil
+			})
+
+			token, err := s.Token(context.Background())
+
+			Convey("Then err should be correct", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "no token returned by the api")
+				So(token, ShouldBeEmpty)
+			})
+		})
+	})
+}
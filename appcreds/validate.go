@@ -0,0 +1,74 @@
+package appcreds
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+const rolePrefix = "@auth:role="
+
+// A ValidationError contains all the problems found
+// while validating the configuration of an appcred.
+type ValidationError struct {
+	Errors []error
+}
+
+func (e *ValidationError) Error() string {
+
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+
+	return fmt.Sprintf("invalid appcred: %s", strings.Join(msgs, "; "))
+}
+
+// Validate verifies that an appcred with the given name, roles
+// and options would be acceptable, without contacting the API.
+// If anything is wrong, it returns a *ValidationError listing
+// every problem found.
+func Validate(name string, roles []string, options ...Option) error {
+
+	cfg := newConfig()
+	for _, opt := range options {
+		opt(&cfg)
+	}
+
+	return cfg.validate(name, roles)
+}
+
+func (c config) validate(name string, roles []string) error {
+
+	verr := &ValidationError{}
+
+	if strings.TrimSpace(name) == "" {
+		verr.Errors = append(verr.Errors, fmt.Errorf("name must not be empty"))
+	}
+
+	if len(roles) == 0 {
+		verr.Errors = append(verr.Errors, fmt.Errorf("roles must not be empty"))
+	}
+
+	for _, role := range roles {
+		if !strings.HasPrefix(role, rolePrefix) || len(role) == len(rolePrefix) {
+			verr.Errors = append(verr.Errors, fmt.Errorf("invalid role '%s': must be in the form '%s<role>'", role, rolePrefix))
+		}
+	}
+
+	for _, subnet := range c.subnets {
+		if _, _, err := net.ParseCIDR(subnet); err != nil {
+			verr.Errors = append(verr.Errors, fmt.Errorf("invalid subnet '%s': %w", subnet, err))
+		}
+	}
+
+	if c.maxValidity < 0 {
+		verr.Errors = append(verr.Errors, fmt.Errorf("invalid max validity '%s': must be positive", c.maxValidity))
+	}
+
+	if len(verr.Errors) > 0 {
+		return verr
+	}
+
+	return nil
+}
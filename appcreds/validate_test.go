@@ -0,0 +1,71 @@
+package appcreds
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestValidate(t *testing.T) {
+
+	Convey("Given I have a valid configuration", t, func() {
+
+		err := Validate(
+			"name",
+			[]string{"@auth:role=enforcer"},
+			OptionSubnets([]string{"10.0.0.0/8", "::1/128"}),
+			OptionMaxValidity(time.Hour),
+		)
+
+		Convey("Then err should be nil", func() {
+			So(err, ShouldBeNil)
+		})
+	})
+
+	Convey("Given I have a configuration without max validity", t, func() {
+
+		err := Validate("name", []string{"@auth:role=enforcer"})
+
+		Convey("Then err should be nil", func() {
+			So(err, ShouldBeNil)
+		})
+	})
+
+	Convey("Given I have a configuration with many problems", t, func() {
+
+		err := Validate(
+			" ",
+			[]string{"enforcer", "@auth:role="},
+			OptionSubnets([]string{"10.0.0.0/8", "not-a-cidr"}),
+			OptionMaxValidity(-time.Hour),
+		)
+
+		Convey("Then err should be a *ValidationError", func() {
+			var verr *ValidationError
+			So(errors.As(err, &verr), ShouldBeTrue)
+			So(len(verr.Errors), ShouldEqual, 5)
+		})
+
+		Convey("Then err should list every problem", func() {
+			So(err.Error(), ShouldEqual, "invalid appcred: "+
+				"name must not be empty; "+
+				"invalid role 'enforcer': must be in the form '@auth:role=<role>'; "+
+				"invalid role '@auth:role=': must be in the form '@auth:role=<role>'; "+
+				"invalid subnet 'not-a-cidr': invalid CIDR address: not-a-cidr; "+
+				"invalid max validity '-1h0m0s': must be positive",
+			)
+		})
+	})
+
+	Convey("Given I have a configuration without roles", t, func() {
+
+		err := Validate("name", nil)
+
+		Convey("Then err should be correct", func() {
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "invalid appcred: roles must not be empty")
+		})
+	})
+}
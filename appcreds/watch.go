@@ -0,0 +1,255 @@
+package appcreds
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"go.aporeto.io/gaia"
+	"go.uber.org/zap"
+)
+
+type holderConfig struct {
+	tokenSource func(*gaia.Credential, *tls.Config) (*TokenSource, error)
+	protection  KeyProtection
+}
+
+// A HolderOption can be used to configure a Holder.
+type HolderOption func(*holderConfig)
+
+// HolderOptionTokenSource configures the Holder to also hold a *TokenSource
+// built by the given function every time the credentials change. The function
+// gets the new credentials and the *tls.Config built from them, and usually
+// creates a manipulator using that *tls.Config to give to NewTokenSource.
+func HolderOptionTokenSource(f func(*gaia.Credential, *tls.Config) (*TokenSource, error)) HolderOption {
+	return func(c *holderConfig) {
+		c.tokenSource = f
+	}
+}
+
+// HolderOptionKeyProtection configures the Holder to decrypt private keys
+// encrypted by EncryptKey using the given KeyProtection. The holder keeps
+// the credentials as given and only uses the clear key to build the
+// *tls.Config. Clear keys are still accepted.
+func HolderOptionKeyProtection(protection KeyProtection) HolderOption {
+	return func(c *holderConfig) {
+		c.protection = protection
+	}
+}
+
+// A Holder holds the current credentials and the *tls.Config and
+// optional *TokenSource built from them. It is safe for concurrent use.
+type Holder struct {
+	cfg         holderConfig
+	creds       *gaia.Credential
+	tlsConfig   *tls.Config
+	tokenSource *TokenSource
+
+	lock sync.RWMutex
+}
+
+// NewHolder returns a new *Holder holding the given credentials.
+// It returns an error if the credentials are not usable.
+func NewHolder(creds *gaia.Credential, options ...HolderOption) (*Holder, error) {
+
+	h := &Holder{}
+	for _, opt := range options {
+		opt(&h.cfg)
+	}
+
+	if err := h.Set(creds); err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+// Credential returns the current credentials.
+func (h *Holder) Credential() *gaia.Credential {
+
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	return h.creds
+}
+
+// TLSConfig returns the *tls.Config built from the current credentials.
+func (h *Holder) TLSConfig() *tls.Config {
+
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	return h.tlsConfig
+}
+
+// TokenSource returns the *TokenSource built from the current credentials.
+// It returns nil if HolderOptionTokenSource was not used.
+func (h *Holder) TokenSource() *TokenSource {
+
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	return h.tokenSource
+}
+
+// Set validates and swaps the current credentials with the given ones.
+// If the given credentials are not usable, an error is returned and
+// the current credentials are kept.
+func (h *Holder) Set(creds *gaia.Credential) error {
+
+	plain := creds
+	if h.cfg.protection != nil && IsKeyEncrypted(creds) {
+		c := *creds
+		if err := DecryptKey(&c, h.cfg.protection); err != nil {
+			return err
+		}
+		plain = &c
+	}
+
+	tlsConfig, err := TLSConfig(plain)
+	if err != nil {
+		return err
+	}
+
+	var tokenSource *TokenSource
+	if h.cfg.tokenSource != nil {
+		if tokenSource, err = h.cfg.tokenSource(creds, tlsConfig); err != nil {
+			return err
+		}
+	}
+
+	h.lock.Lock()
+	h.creds = creds
+	h.tlsConfig = tlsConfig
+	h.tokenSource = tokenSource
+	h.lock.Unlock()
+
+	return nil
+}
+
+// Load reads the credentials stored as JSON in the given file.
+func Load(path string) (*gaia.Credential, error) {
+
+	data, err := ioutil.ReadFile(path) // #nosec
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeCredential(data)
+}
+
+// LoadEncrypted reads the credentials stored as JSON in the given file
+// and decrypts their private key using the given KeyProtection if it
+// has been encrypted by EncryptKey. Clear keys are returned as is.
+func LoadEncrypted(path string, protection KeyProtection) (*gaia.Credential, error) {
+
+	creds, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !IsKeyEncrypted(creds) {
+		return creds, nil
+	}
+
+	if err := DecryptKey(creds, protection); err != nil {
+		return nil, err
+	}
+
+	return creds, nil
+}
+
+// Watch loads the credentials stored in the given file in a new *Holder, then
+// polls the file every interval until the context is done. When the content
+// of the file changes, the new credentials are validated then swapped in the
+// holder. Invalid updates are logged and the current credentials are kept.
+//
+// Polling is used rather than file system notifications so updates
+// done by swapping symlinks, like Kubernetes does for mounted secrets,
+// are correctly detected. The interval must be positive.
+//
+// The holder can be configured using the given options.
+func Watch(ctx context.Context, path string, interval time.Duration, options ...HolderOption) (*Holder, error) {
+
+	if interval <= 0 {
+		return nil, fmt.Errorf("invalid watch interval '%s': must be positive", interval)
+	}
+
+	data, err := ioutil.ReadFile(path) // #nosec
+	if err != nil {
+		return nil, err
+	}
+
+	creds, err := decodeCredential(data)
+	if err != nil {
+		return nil, err
+	}
+
+	h, err := NewHolder(creds, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	go watch(ctx, h, path, interval, sha256.Sum256(data))
+
+	return h, nil
+}
+
+func watch(ctx context.Context, h *Holder, path string, interval time.Duration, last [sha256.Size]byte) {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+
+		case <-ticker.C:
+
+			data, err := ioutil.ReadFile(path) // #nosec
+			if err != nil {
+				zap.L().Error("Unable to read credentials", zap.String("path", path), zap.Error(err))
+				continue
+			}
+
+			sum := sha256.Sum256(data)
+			if bytes.Equal(sum[:], last[:]) {
+				continue
+			}
+
+			// We remember the content even if it is invalid
+			// so we only complain once per bad update.
+			last = sum
+
+			creds, err := decodeCredential(data)
+			if err == nil {
+				err = h.Set(creds)
+			}
+
+			if err != nil {
+				zap.L().Error("Rejected invalid credentials update", zap.String("path", path), zap.Error(err))
+				continue
+			}
+
+			zap.L().Info("Credentials reloaded", zap.String("path", path))
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func decodeCredential(data []byte) (*gaia.Credential, error) {
+
+	creds := gaia.NewCredential()
+	if err := json.Unmarshal(data, creds); err != nil {
+		return nil, fmt.Errorf("unable to decode credentials: %w", err)
+	}
+
+	return creds, nil
+}
@@ -0,0 +1,277 @@
+package appcreds
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.aporeto.io/gaia"
+	"go.aporeto.io/manipulate/maniptest"
+)
+
+func writeTestCredentials(path string, creds *gaia.Credential) {
+
+	data, err := json.Marshal(creds)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		panic(err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+
+	Convey("Given I have a credentials file", t, func() {
+
+		dir, err := ioutil.TempDir("", "appcreds")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir) // nolint: errcheck
+
+		path := filepath.Join(dir, "creds.json")
+		creds := makeTestCredentials(time.Now().Add(time.Hour))
+		writeTestCredentials(path, creds)
+
+		Convey("When I call Load", func() {
+
+			loaded, err := Load(path)
+
+			Convey("Then the credentials should be correct", func() {
+				So(err, ShouldBeNil)
+				So(loaded, ShouldResemble, creds)
+			})
+		})
+
+		Convey("When I call LoadEncrypted on a clear key", func() {
+
+			loaded, err := LoadEncrypted(path, ProtectWithPassphrase([]byte("secret")))
+
+			Convey("Then the credentials should be correct", func() {
+				So(err, ShouldBeNil)
+				So(loaded, ShouldResemble, creds)
+			})
+		})
+
+		Convey("When I call LoadEncrypted on an encrypted key", func() {
+
+			encrypted := *creds
+			So(EncryptKey(&encrypted, ProtectWithPassphrase([]byte("secret"))), ShouldBeNil)
+			writeTestCredentials(path, &encrypted)
+
+			loaded, err := LoadEncrypted(path, ProtectWithPassphrase([]byte("secret")))
+
+			Convey("Then the key should be decrypted", func() {
+				So(err, ShouldBeNil)
+				So(loaded, ShouldResemble, creds)
+			})
+
+			Convey("When I use the wrong passphrase", func() {
+
+				loaded, err := LoadEncrypted(path, ProtectWithPassphrase([]byte("nope")))
+
+				Convey("Then err should not be nil", func() {
+					So(err, ShouldNotBeNil)
+					So(err.Error(), ShouldEqual, "unable to decrypt private key: wrong protection or corrupted data")
+					So(loaded, ShouldBeNil)
+				})
+			})
+		})
+
+		Convey("When I call Load on an invalid file", func() {
+
+			So(ioutil.WriteFile(path, []byte("{"), 0600), ShouldBeNil)
+
+			loaded, err := Load(path)
+
+			Convey("Then err should not be nil", func() {
+				So(err, ShouldNotBeNil)
+				So(loaded, ShouldBeNil)
+			})
+		})
+	})
+}
+
+func TestWatch(t *testing.T) {
+
+	Convey("Given I have a credentials file", t, func() {
+
+		dir, err := ioutil.TempDir("", "appcreds")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir) // nolint: errcheck
+
+		path := filepath.Join(dir, "creds.json")
+		creds := makeTestCredentials(time.Now().Add(time.Hour))
+		writeTestCredentials(path, creds)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		Convey("When I call Watch", func() {
+
+			h, err := Watch(ctx, path, 10*time.Millisecond)
+
+			Convey("Then the holder should hold the credentials", func() {
+				So(err, ShouldBeNil)
+				So(h.Credential(), ShouldResemble, creds)
+				So(h.TLSConfig(), ShouldNotBeNil)
+			})
+
+			Convey("When I write new valid credentials", func() {
+
+				next := makeTestCredentials(time.Now().Add(2 * time.Hour))
+				writeTestCredentials(path, next)
+
+				Convey("Then the holder should be updated", func() {
+					So(eventually(func() bool { return h.Credential().Certificate == next.Certificate }), ShouldBeTrue)
+				})
+			})
+
+			Convey("When I write invalid credentials", func() {
+
+				tlsConfig := h.TLSConfig()
+
+				bad := makeTestCredentials(time.Now().Add(2 * time.Hour))
+				bad.CertificateKey = creds.CertificateKey
+				writeTestCredentials(path, bad)
+				time.Sleep(100 * time.Millisecond)
+
+				Convey("Then the holder should keep the current credentials", func() {
+					So(h.Credential(), ShouldResemble, creds)
+					So(h.TLSConfig(), ShouldEqual, tlsConfig)
+				})
+			})
+		})
+
+		Convey("When I call Watch on invalid credentials", func() {
+
+			bad := makeTestCredentials(time.Now().Add(time.Hour))
+			bad.CertificateKey = ""
+			writeTestCredentials(path, bad)
+
+			h, err := Watch(ctx, path, 10*time.Millisecond)
+
+			Convey("Then err should not be nil", func() {
+				So(err, ShouldNotBeNil)
+				So(h, ShouldBeNil)
+			})
+		})
+
+		Convey("When I call Watch on an encrypted key with its protection", func() {
+
+			encrypted := *creds
+			So(EncryptKey(&encrypted, ProtectWithPassphrase([]byte("secret"))), ShouldBeNil)
+			writeTestCredentials(path, &encrypted)
+
+			h, err := Watch(ctx, path, 10*time.Millisecond, HolderOptionKeyProtection(ProtectWithPassphrase([]byte("secret"))))
+
+			Convey("Then the holder should hold the credentials as given", func() {
+				So(err, ShouldBeNil)
+				So(h.Credential(), ShouldResemble, &encrypted)
+				So(h.TLSConfig(), ShouldNotBeNil)
+			})
+		})
+
+		Convey("When I call Watch on an encrypted key without its protection", func() {
+
+			encrypted := *creds
+			So(EncryptKey(&encrypted, ProtectWithPassphrase([]byte("secret"))), ShouldBeNil)
+			writeTestCredentials(path, &encrypted)
+
+			h, err := Watch(ctx, path, 10*time.Millisecond)
+
+			Convey("Then err should not be nil", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "unable to decode private key: key is encrypted")
+				So(h, ShouldBeNil)
+			})
+		})
+
+		Convey("When I call Watch with a non positive interval", func() {
+
+			h, err := Watch(ctx, path, 0)
+
+			Convey("Then err should not be nil", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "invalid watch interval '0s': must be positive")
+				So(h, ShouldBeNil)
+			})
+		})
+
+		Convey("When I call Watch with a token source", func() {
+
+			var calls int
+			h, err := Watch(ctx, path, 10*time.Millisecond, HolderOptionTokenSource(
+				func(c *gaia.Credential, tlsConfig *tls.Config) (*TokenSource, error) {
+					calls++
+					if tlsConfig == nil {
+						return nil, errors.New("missing tls config")
+					}
+					return NewTokenSource(gaia.NewAppCredential(), maniptest.NewTestManipulator())
+				},
+			))
+
+			Convey("Then the holder should hold a token source", func() {
+				So(err, ShouldBeNil)
+				So(h.TokenSource(), ShouldNotBeNil)
+				So(calls, ShouldEqual, 1)
+			})
+
+			Convey("When I write new valid credentials", func() {
+
+				first := h.TokenSource()
+				next := makeTestCredentials(time.Now().Add(2 * time.Hour))
+				writeTestCredentials(path, next)
+
+				Convey("Then the token source should be rebuilt", func() {
+					So(eventually(func() bool { return h.TokenSource() != first }), ShouldBeTrue)
+				})
+			})
+		})
+
+		Convey("When I call Watch with a failing token source", func() {
+
+			h, err := Watch(ctx, path, 10*time.Millisecond, HolderOptionTokenSource(
+				func(*gaia.Credential, *tls.Config) (*TokenSource, error) {
+					return nil, errors.New("boom")
+				},
+			))
+
+			Convey("Then err should not be nil", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "boom")
+				So(h, ShouldBeNil)
+			})
+		})
+
+		Convey("When I call Watch on a missing file", func() {
+
+			h, err := Watch(ctx, filepath.Join(dir, "missing"), 10*time.Millisecond)
+
+			Convey("Then err should not be nil", func() {
+				So(err, ShouldNotBeNil)
+				So(h, ShouldBeNil)
+			})
+		})
+	})
+}
+
+func eventually(cond func() bool) bool {
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	return false
+}
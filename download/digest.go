@@ -0,0 +1,98 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package download
+
+import (
+	"crypto/sha1" // #nosec
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+// An Algorithm is a digest algorithm used to verify downloaded binaries.
+// Algorithms are ordered by strength.
+type Algorithm int
+
+// Various values of Algorithm.
+const (
+	AlgorithmSHA1 Algorithm = iota
+	AlgorithmSHA256
+	AlgorithmSHA512
+)
+
+func (a Algorithm) String() string {
+
+	switch a {
+	case AlgorithmSHA1:
+		return "sha1"
+	case AlgorithmSHA256:
+		return "sha256"
+	case AlgorithmSHA512:
+		return "sha512"
+	default:
+		return "unknown"
+	}
+}
+
+func (a Algorithm) new() hash.Hash {
+
+	switch a {
+	case AlgorithmSHA256:
+		return sha256.New()
+	case AlgorithmSHA512:
+		return sha512.New()
+	default:
+		return sha1.New() // #nosec
+	}
+}
+
+// A digest is a parsed Variant signature.
+type digest struct {
+	algorithm Algorithm
+	sum       []byte
+}
+
+// parseDigest parses a digest in the form <algorithm>:<hex>.
+// For backward compatibility, a digest without algorithm is a SHA-1.
+func parseDigest(s string) (digest, error) {
+
+	algorithm := AlgorithmSHA1
+	value := s
+
+	if i := strings.IndexByte(s, ':'); i >= 0 {
+		switch strings.ToLower(s[:i]) {
+		case "sha1":
+			algorithm = AlgorithmSHA1
+		case "sha256":
+			algorithm = AlgorithmSHA256
+		case "sha512":
+			algorithm = AlgorithmSHA512
+		default:
+			return digest{}, fmt.Errorf("unsupported digest algorithm: %s", s[:i])
+		}
+		value = s[i+1:]
+	}
+
+	sum, err := hex.DecodeString(value)
+	if err != nil {
+		return digest{}, fmt.Errorf("invalid %s digest: %s", algorithm, err)
+	}
+
+	if len(sum) != algorithm.new().Size() {
+		return digest{}, fmt.Errorf("invalid %s digest: wrong length %d", algorithm, len(sum))
+	}
+
+	return digest{algorithm: algorithm, sum: sum}, nil
+}
@@ -0,0 +1,69 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package download
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseDigest(t *testing.T) {
+
+	Convey("Given I have various digests", t, func() {
+
+		sha1Hex := strings.Repeat("ab", 20)
+		sha256Hex := strings.Repeat("ab", 32)
+		sha512Hex := strings.Repeat("ab", 64)
+
+		Convey("Then a bare hex digest should be a sha1", func() {
+			d, err := parseDigest(sha1Hex)
+			So(err, ShouldBeNil)
+			So(d.algorithm, ShouldEqual, AlgorithmSHA1)
+			So(len(d.sum), ShouldEqual, 20)
+		})
+
+		Convey("Then prefixed digests should be parsed", func() {
+
+			d, err := parseDigest("sha1:" + sha1Hex)
+			So(err, ShouldBeNil)
+			So(d.algorithm, ShouldEqual, AlgorithmSHA1)
+
+			d, err = parseDigest("sha256:" + sha256Hex)
+			So(err, ShouldBeNil)
+			So(d.algorithm, ShouldEqual, AlgorithmSHA256)
+
+			d, err = parseDigest("SHA512:" + sha512Hex)
+			So(err, ShouldBeNil)
+			So(d.algorithm, ShouldEqual, AlgorithmSHA512)
+		})
+
+		Convey("Then an unsupported algorithm should be refused", func() {
+			_, err := parseDigest("md5:" + strings.Repeat("ab", 16))
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "unsupported digest algorithm: md5")
+		})
+
+		Convey("Then an invalid hex value should be refused", func() {
+			_, err := parseDigest("sha256:not-hex")
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldStartWith, "invalid sha256 digest: ")
+		})
+
+		Convey("Then a digest of the wrong length should be refused", func() {
+			_, err := parseDigest("sha256:" + sha1Hex)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "invalid sha256 digest: wrong length 20")
+		})
+	})
+}
@@ -12,14 +12,18 @@
 package download
 
 import (
+	"bytes"
 	"context"
-	"crypto/sha1" // #nosec
+	"crypto/sha512"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"math/rand"
 	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/blang/semver"
@@ -31,37 +35,87 @@ import (
 type Manifest map[string]Component
 
 // RetrieveManifest fetch the manifest at the given URL.
-func RetrieveManifest(ctx context.Context, url string) (Manifest, error) {
+// If OptionPublicKeys is given, the detached signature of the
+// manifest is verified before it is returned.
+func RetrieveManifest(ctx context.Context, url string, options ...Option) (Manifest, error) {
 
-	out, err := retry.Retry(
+	cfg := newConfig()
+	for _, opt := range options {
+		opt(&cfg)
+	}
+
+	if err := cfg.checkPublicKeys(); err != nil {
+		return Manifest{}, err
+	}
+
+	resp, err := retry.Do(
 		ctx,
-		func() (interface{}, error) {
-			resp, err := http.Get(fmt.Sprintf("%s?nocache=%d", url, rand.Int())) // #nosec
+		func(ctx context.Context) (*http.Response, error) {
+			manifestURL, err := noCacheURL(url)
+			if err != nil {
+				return nil, retry.Permanent(err)
+			}
+
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, manifestURL, nil)
+			if err != nil {
+				return nil, retry.Permanent(err)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
 			if err != nil {
 				return nil, err
 			}
 
 			if resp.StatusCode != http.StatusOK {
-				return nil, fmt.Errorf("unable to download manifest: %s", resp.Status)
+				resp.Body.Close() // nolint: errcheck
+				return nil, retry.WithRetryAfter(resp, fmt.Errorf("unable to download manifest: %s", resp.Status))
 			}
 
 			return resp, nil
 		},
-		func(err error) error {
-			zap.L().Warn("Unable to download manifest. retrying in 3s", zap.Error(err))
-			return nil
-		},
+		makeRetryOptions(cfg, func(a retry.Attempt) {
+			zap.L().Warn("Unable to download manifest. retrying",
+				zap.Int("attempt", a.Number),
+				zap.Duration("retry-in", a.NextDelay),
+				zap.Error(a.Err),
+			)
+		})...,
 	)
 
 	if err != nil {
 		return Manifest{}, err
 	}
 
-	resp := out.(*http.Response)
+	defer resp.Body.Close() // nolint: errcheck
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	if cfg.verifySignatures {
+
+		sigURL, err := signatureURL(url)
+		if err != nil {
+			return Manifest{}, err
+		}
+
+		if sigURL, err = noCacheURL(sigURL); err != nil {
+			return Manifest{}, err
+		}
+
+		signature, err := retrieveSignature(ctx, sigURL, cfg)
+		if err != nil {
+			return Manifest{}, err
+		}
+
+		sum := sha512.Sum512(data)
+		if err = verifySignature(cfg.publicKeys, sum[:], signature); err != nil {
+			return Manifest{}, err
+		}
+	}
 
 	manifest := Manifest{}
-	defer resp.Body.Close() // nolint: errcheck
-	if err = json.NewDecoder(resp.Body).Decode(&manifest); err != nil {
+	if err = json.Unmarshal(data, &manifest); err != nil {
 		return Manifest{}, err
 	}
 
@@ -113,53 +167,166 @@ func NewVariant(url, signature string) Variant {
 }
 
 // Binary downloads and saves the binary at the given url to the given dest with the given mode.
-func Binary(ctx context.Context, url string, dest string, mode os.FileMode, signature string) error {
+// The binary is streamed to a temporary file next to dest, then atomically renamed
+// to dest once its signature is verified, so a failure leaves any previous dest intact.
+//
+// The signature is a digest of the binary in the form <algorithm>:<hex>, where
+// algorithm is sha1, sha256 or sha512. A digest without algorithm is a SHA-1.
+// An empty signature skips the verification, unless OptionMinimumAlgorithm is given.
+//
+// If OptionPublicKeys is given, the detached signature of the binary is
+// downloaded first, and dest is only replaced if the signature is verified.
+//
+// If the download is interrupted, or receives no data for the duration given
+// by OptionIdleTimeout, the next attempt resumes it using a Range
+// request when the server advertises Accept-Ranges and a strong ETag. If the
+// binary changed in the meantime, the download restarts from the beginning.
+func Binary(ctx context.Context, url string, dest string, mode os.FileMode, signature string, options ...Option) error {
+
+	cfg := newConfig()
+	for _, opt := range options {
+		opt(&cfg)
+	}
 
-	out, err := retry.Retry(
-		ctx,
-		func() (interface{}, error) {
-			resp, err := http.Get(url) // #nosec
-			if err != nil {
-				return nil, err
-			}
+	if err := cfg.checkPublicKeys(); err != nil {
+		return err
+	}
 
-			if resp.StatusCode != 200 {
-				return nil, fmt.Errorf("unable to find the request binary: %s", resp.Status)
-			}
+	var d digest
+	if signature != "" {
 
-			return resp, nil
-		},
-		func(err error) error {
-			zap.L().Warn("Unable to download binary. retrying in 3s", zap.Error(err))
-			return nil
+		var err error
+		if d, err = parseDigest(signature); err != nil {
+			return err
+		}
+
+		if d.algorithm < cfg.minAlgorithm {
+			return fmt.Errorf("digest algorithm %s is weaker than the required %s", d.algorithm, cfg.minAlgorithm)
+		}
+
+	} else if cfg.requireDigest {
+		return fmt.Errorf("missing signature: a %s digest is required", cfg.minAlgorithm)
+	}
+
+	var detached []byte
+	if cfg.verifySignatures {
+		sigURL, err := signatureURL(url)
+		if err != nil {
+			return err
+		}
+
+		if detached, err = retrieveSignature(ctx, sigURL, cfg); err != nil {
+			return err
+		}
+	}
+
+	h := d.algorithm.new()
+	sh := sha512.New()
+
+	fill := func(f *os.File) error {
+
+		p := newPartialFile(f, h, sh)
+
+		_, err := retry.Do(
+			ctx,
+			func(ctx context.Context) (struct{}, error) {
+				return struct{}{}, p.download(ctx, url, cfg.idleTimeout)
+			},
+			makeRetryOptions(cfg, func(a retry.Attempt) {
+				zap.L().Warn("Unable to download binary. retrying",
+					zap.Int("attempt", a.Number),
+					zap.Int64("received", p.size),
+					zap.Duration("retry-in", a.NextDelay),
+					zap.Error(a.Err),
+				)
+			})...,
+		)
+
+		return err
+	}
+
+	return writeFile(dest, mode, fill, func() error {
+
+		if signature != "" && !bytes.Equal(h.Sum(nil), d.sum) {
+			return fmt.Errorf("invalid signature")
+		}
+
+		if cfg.verifySignatures {
+			return verifySignature(cfg.publicKeys, sh.Sum(nil), detached)
+		}
+
+		return nil
+	})
+}
+
+// noCacheURL adds a random nocache parameter to
+// the query of the given URL to bypass caches.
+func noCacheURL(rawURL string) (string, error) {
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	q := u.Query()
+	q.Set("nocache", strconv.Itoa(rand.Int())) // #nosec
+	u.RawQuery = q.Encode()
+
+	return u.String(), nil
+}
+
+// makeRetryOptions returns the options used to retry the requests,
+// logging the failed attempts with the given hook.
+func makeRetryOptions(cfg config, hook func(retry.Attempt)) []retry.Option {
+
+	return append(
+		[]retry.Option{
+			retry.OptionHook(hook),
+			retry.OptionTracing(),
 		},
+		cfg.retryOptions...,
 	)
+}
+
+// writeFile creates a temporary file in the directory of dest and calls
+// fill to write its content. Once fill returns, it calls verify, then
+// syncs the file and renames it to dest with the given mode. If anything
+// fails, the temporary file is removed and dest is left untouched.
+func writeFile(dest string, mode os.FileMode, fill func(*os.File) error, verify func() error) (err error) {
 
+	f, err := ioutil.TempFile(filepath.Dir(dest), fmt.Sprintf(".%s.", filepath.Base(dest)))
 	if err != nil {
 		return err
 	}
 
-	resp := out.(*http.Response)
+	defer func() {
+		if err != nil {
+			f.Close()           // nolint: errcheck
+			os.Remove(f.Name()) // nolint: errcheck
+		}
+	}()
 
-	defer resp.Body.Close() // nolint: errcheck
-	data, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
+	if err = fill(f); err != nil {
 		return err
 	}
 
-	if signature != "" {
+	if err = verify(); err != nil {
+		return err
+	}
 
-		h := sha1.New() // #nosec
-		if _, err = h.Write(data); err != nil {
-			return err
-		}
+	if err = f.Chmod(mode); err != nil {
+		return err
+	}
 
-		if fmt.Sprintf("%x", h.Sum(nil)) != signature {
-			return fmt.Errorf("invalid signature")
-		}
+	if err = f.Sync(); err != nil {
+		return err
+	}
+
+	if err = f.Close(); err != nil {
+		return err
 	}
 
-	return ioutil.WriteFile(dest, data, mode)
+	return os.Rename(f.Name(), dest)
 }
 
 // IsOutdated checks if the given current is outdated relatively to the second using semver.
@@ -0,0 +1,179 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package download
+
+import (
+	"context"
+	"crypto/sha1" // #nosec
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBinary(t *testing.T) {
+
+	Convey("Given I have a server serving a binary and a previous binary on disk", t, func() {
+
+		payload := []byte("#!/bin/sh\necho new\n")
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write(payload) // nolint: errcheck
+		}))
+		defer ts.Close()
+
+		dir, err := ioutil.TempDir("", "download")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir) // nolint: errcheck
+
+		dest := filepath.Join(dir, "enforcerd")
+		So(ioutil.WriteFile(dest, []byte("old"), 0600), ShouldBeNil)
+
+		Convey("When I download it with the correct signature", func() {
+
+			err := Binary(context.Background(), ts.URL, dest, 0750, fmt.Sprintf("%x", sha1.Sum(payload))) // #nosec
+
+			Convey("Then dest should be replaced with the requested mode", func() {
+				So(err, ShouldBeNil)
+				data, _ := ioutil.ReadFile(dest)
+				So(data, ShouldResemble, payload)
+				info, _ := os.Stat(dest)
+				So(info.Mode().Perm(), ShouldEqual, os.FileMode(0750))
+			})
+
+			Convey("Then no temporary file should be left", func() {
+				files, _ := ioutil.ReadDir(dir)
+				So(len(files), ShouldEqual, 1)
+			})
+		})
+
+		Convey("When I download it with an incorrect signature", func() {
+
+			err := Binary(context.Background(), ts.URL, dest, 0750, fmt.Sprintf("%x", sha1.Sum([]byte("other")))) // #nosec
+
+			Convey("Then the previous binary should be left intact", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "invalid signature")
+				data, _ := ioutil.ReadFile(dest)
+				So(string(data), ShouldEqual, "old")
+				files, _ := ioutil.ReadDir(dir)
+				So(len(files), ShouldEqual, 1)
+			})
+		})
+
+		Convey("When I download it with a sha512 digest", func() {
+
+			err := Binary(context.Background(), ts.URL, dest, 0750, fmt.Sprintf("sha512:%x", sha512.Sum512(payload)))
+
+			Convey("Then dest should be replaced", func() {
+				So(err, ShouldBeNil)
+				data, _ := ioutil.ReadFile(dest)
+				So(data, ShouldResemble, payload)
+			})
+		})
+
+		Convey("When I download it with an incorrect sha256 digest", func() {
+
+			err := Binary(context.Background(), ts.URL, dest, 0750, fmt.Sprintf("sha256:%x", sha256.Sum256([]byte("other"))))
+
+			Convey("Then the previous binary should be left intact", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "invalid signature")
+				data, _ := ioutil.ReadFile(dest)
+				So(string(data), ShouldEqual, "old")
+			})
+		})
+
+		Convey("When I require at least sha256 and give a sha1 digest", func() {
+
+			err := Binary(
+				context.Background(),
+				ts.URL,
+				dest,
+				0750,
+				fmt.Sprintf("%x", sha1.Sum(payload)), // #nosec
+				OptionMinimumAlgorithm(AlgorithmSHA256),
+			)
+
+			Convey("Then it should be refused", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "digest algorithm sha1 is weaker than the required sha256")
+				data, _ := ioutil.ReadFile(dest)
+				So(string(data), ShouldEqual, "old")
+			})
+		})
+
+		Convey("When I require at least sha256 and give no signature", func() {
+
+			err := Binary(context.Background(), ts.URL, dest, 0750, "", OptionMinimumAlgorithm(AlgorithmSHA256))
+
+			Convey("Then it should be refused", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "missing signature: a sha256 digest is required")
+			})
+		})
+
+		Convey("When I require at least sha256 and give a sha512 digest", func() {
+
+			err := Binary(
+				context.Background(),
+				ts.URL,
+				dest,
+				0750,
+				fmt.Sprintf("sha512:%x", sha512.Sum512(payload)),
+				OptionMinimumAlgorithm(AlgorithmSHA256),
+			)
+
+			Convey("Then it should be accepted", func() {
+				So(err, ShouldBeNil)
+			})
+		})
+	})
+}
+
+func TestRetrieveManifest_Cancel(t *testing.T) {
+
+	Convey("Given I have a server that stalls", t, func() {
+
+		stop := make(chan struct{})
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-stop:
+			case <-r.Context().Done():
+			}
+		}))
+		defer ts.Close()
+		defer close(stop)
+
+		Convey("When I retrieve the manifest and the context is done", func() {
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			_, err := RetrieveManifest(ctx, ts.URL)
+
+			Convey("Then it should return as soon as the context is done", func() {
+				So(errors.Is(err, context.DeadlineExceeded), ShouldBeTrue)
+				So(time.Since(start), ShouldBeLessThan, 5*time.Second)
+			})
+		})
+	})
+}
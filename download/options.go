@@ -0,0 +1,86 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package download
+
+import (
+	"crypto"
+	"time"
+
+	"go.aporeto.io/addedeffect/retry"
+)
+
+const defaultIdleTimeout = 30 * time.Second
+
+type config struct {
+	minAlgorithm     Algorithm
+	requireDigest    bool
+	publicKeys       []crypto.PublicKey
+	verifySignatures bool
+	retryOptions     []retry.Option
+	idleTimeout      time.Duration
+}
+
+func newConfig() config {
+	return config{
+		minAlgorithm: AlgorithmSHA1,
+		idleTimeout:  defaultIdleTimeout,
+	}
+}
+
+// An Option can be used to configure a download.
+type Option func(*config)
+
+// OptionMinimumAlgorithm requires the signature of the binary to be a
+// digest using at least the given algorithm. When set, a binary without
+// signature or with a weaker digest is refused before being downloaded.
+// Default is to accept any digest, and no signature at all.
+func OptionMinimumAlgorithm(algorithm Algorithm) Option {
+	return func(c *config) {
+		c.minAlgorithm = algorithm
+		c.requireDigest = true
+	}
+}
+
+// OptionPublicKeys sets the trusted public keys used to verify the
+// detached signatures of the manifest or the binary, downloaded from
+// their URL with SignatureSuffix. When set, a download without a
+// signature verified by one of the keys fails. Supported keys are
+// ed25519.PublicKey and *ecdsa.PublicKey. Any other key, or no key
+// at all, makes the download fail before anything is requested.
+// See Sign for the format.
+// Default is to not verify signatures.
+func OptionPublicKeys(keys ...crypto.PublicKey) Option {
+	return func(c *config) {
+		c.publicKeys = keys
+		c.verifySignatures = true
+	}
+}
+
+// OptionRetry sets additional options used to retry the requests, like
+// retry.OptionMaxAttempts, retry.OptionBackoff or retry.OptionClock. They
+// are applied after the default ones, so they can override them.
+// Default is to retry until the context is done, every 3 seconds.
+func OptionRetry(options ...retry.Option) Option {
+	return func(c *config) {
+		c.retryOptions = append(c.retryOptions, options...)
+	}
+}
+
+// OptionIdleTimeout sets the maximum time to wait for data while
+// downloading a binary. When it expires, the attempt fails and the
+// next one resumes the download where it stopped, when possible.
+// Default is 30s. A value of 0 or less means no limit.
+func OptionIdleTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		c.idleTimeout = timeout
+	}
+}
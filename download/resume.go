@@ -0,0 +1,228 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package download
+
+import (
+	"context"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.aporeto.io/addedeffect/retry"
+)
+
+// A partialFile is a binary being downloaded. It keeps what
+// was already received across attempts, so an interrupted
+// download can be resumed using a Range request.
+type partialFile struct {
+	file   *os.File
+	hashes []hash.Hash
+	size   int64
+	etag   string
+	ranges bool
+}
+
+func newPartialFile(file *os.File, hashes ...hash.Hash) *partialFile {
+	return &partialFile{
+		file:   file,
+		hashes: hashes,
+	}
+}
+
+// Write writes the given data to the file and the hashes.
+// Errors writing the file are permanent.
+func (p *partialFile) Write(data []byte) (int, error) {
+
+	n, err := p.file.Write(data)
+	for _, h := range p.hashes {
+		h.Write(data[:n]) // nolint: errcheck
+	}
+	p.size += int64(n)
+
+	if err != nil {
+		return n, retry.Permanent(err)
+	}
+
+	return n, nil
+}
+
+// download downloads the binary at the given url, resuming
+// where the previous call stopped when the server allows it.
+// If no data is received for idleTimeout, the download is
+// aborted so the next attempt can resume it.
+func (p *partialFile) download(ctx context.Context, url string, idleTimeout time.Duration) error {
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	w := newWatchdog(idleTimeout, cancel)
+	defer w.stop()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return retry.Permanent(err)
+	}
+
+	if p.resumable() {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", p.size))
+		req.Header.Set("If-Range", p.etag)
+	}
+
+	resp, err := http.DefaultClient.Do(req) // #nosec
+	if err != nil {
+		return w.wrap(err)
+	}
+	defer resp.Body.Close() // nolint: errcheck
+
+	switch resp.StatusCode {
+
+	case http.StatusOK:
+		if err := p.reset(); err != nil {
+			return retry.Permanent(err)
+		}
+		p.etag = resp.Header.Get("ETag")
+		p.ranges = resp.Header.Get("Accept-Ranges") == "bytes"
+
+	case http.StatusPartialContent:
+		if start, ok := contentRangeStart(resp.Header.Get("Content-Range")); !ok || start != p.size {
+			if err := p.reset(); err != nil {
+				return retry.Permanent(err)
+			}
+			return fmt.Errorf("unexpected content range: %s", resp.Header.Get("Content-Range"))
+		}
+
+	case http.StatusRequestedRangeNotSatisfiable:
+		if err := p.reset(); err != nil {
+			return retry.Permanent(err)
+		}
+		return fmt.Errorf("unable to resume the request binary: %s", resp.Status)
+
+	default:
+		return retry.WithRetryAfter(resp, fmt.Errorf("unable to find the request binary: %s", resp.Status))
+	}
+
+	_, err = io.Copy(p, w.reader(resp.Body))
+
+	return w.wrap(err)
+}
+
+// resumable returns true if the next request can ask for the missing
+// part only. The ETag must be strong to be used with If-Range.
+func (p *partialFile) resumable() bool {
+	return p.size > 0 && p.ranges && p.etag != "" && !strings.HasPrefix(p.etag, "W/")
+}
+
+// reset drops what was received so far.
+func (p *partialFile) reset() error {
+
+	if _, err := p.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	if err := p.file.Truncate(0); err != nil {
+		return err
+	}
+
+	for _, h := range p.hashes {
+		h.Reset()
+	}
+
+	p.size = 0
+	p.etag = ""
+	p.ranges = false
+
+	return nil
+}
+
+// A watchdog cancels a download when no data
+// has been received for the given timeout.
+type watchdog struct {
+	timeout time.Duration
+	timer   *time.Timer
+	fired   int32
+}
+
+// newWatchdog returns a watchdog calling cancel after the
+// given timeout, unless it is kicked in the meantime. A
+// timeout of 0 or less returns a watchdog doing nothing.
+func newWatchdog(timeout time.Duration, cancel func()) *watchdog {
+
+	w := &watchdog{timeout: timeout}
+	if timeout > 0 {
+		w.timer = time.AfterFunc(timeout, func() {
+			atomic.StoreInt32(&w.fired, 1)
+			cancel()
+		})
+	}
+
+	return w
+}
+
+// reader returns an io.Reader kicking the watchdog on each read.
+func (w *watchdog) reader(r io.Reader) io.Reader {
+	return watchdogReader{reader: r, watchdog: w}
+}
+
+// wrap replaces the given error by a clearer one
+// if the download was cancelled by the watchdog.
+func (w *watchdog) wrap(err error) error {
+
+	if err != nil && atomic.LoadInt32(&w.fired) == 1 {
+		return fmt.Errorf("download stalled: no data received for %s", w.timeout)
+	}
+
+	return err
+}
+
+func (w *watchdog) kick() {
+	if w.timer != nil {
+		w.timer.Reset(w.timeout)
+	}
+}
+
+func (w *watchdog) stop() {
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+}
+
+type watchdogReader struct {
+	reader   io.Reader
+	watchdog *watchdog
+}
+
+func (r watchdogReader) Read(data []byte) (int, error) {
+
+	n, err := r.reader.Read(data)
+	if n > 0 {
+		r.watchdog.kick()
+	}
+
+	return n, err
+}
+
+// contentRangeStart returns the first byte position
+// of the given Content-Range header value.
+func contentRangeStart(value string) (int64, bool) {
+
+	var start, end int64
+	if _, err := fmt.Sscanf(value, "bytes %d-%d/", &start, &end); err != nil {
+		return 0, false
+	}
+
+	return start, true
+}
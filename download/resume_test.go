@@ -0,0 +1,214 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package download
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.aporeto.io/addedeffect/retry"
+)
+
+// flakyServer serves a binary, but the first request
+// is interrupted after sending half of it. If stall is
+// true, it stops sending data instead.
+type flakyServer struct {
+	first   []byte
+	second  []byte
+	etag    string
+	stall   bool
+	calls   int
+	ranges  []string
+	ifRange []string
+	lock    sync.Mutex
+}
+
+func (s *flakyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	s.lock.Lock()
+	s.calls++
+	calls := s.calls
+	s.ranges = append(s.ranges, r.Header.Get("Range"))
+	s.ifRange = append(s.ifRange, r.Header.Get("If-Range"))
+	s.lock.Unlock()
+
+	if calls == 1 {
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Accept-Ranges", "bytes")
+		w.Header().Set("Content-Length", strconv.Itoa(len(s.first)))
+		w.Write(s.first[:len(s.first)/2]) // nolint: errcheck
+		w.(http.Flusher).Flush()
+		if s.stall {
+			<-r.Context().Done()
+			return
+		}
+		panic(http.ErrAbortHandler)
+	}
+
+	w.Header().Set("ETag", s.etag)
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(s.second))
+}
+
+func TestBinary_Resume(t *testing.T) {
+
+	Convey("Given I have a fake clock and a destination", t, func() {
+
+		fakeClock := retry.NewFakeClock(time.Now())
+
+		dir, err := ioutil.TempDir("", "download")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir) // nolint: errcheck
+
+		dest := filepath.Join(dir, "enforcerd")
+
+		binary := bytes.Repeat([]byte("0123456789abcdef"), 4096)
+
+		download := func(url string, signature string) error {
+
+			errs := make(chan error)
+			go func() {
+				errs <- Binary(context.Background(), url, dest, 0750, signature, OptionRetry(retry.OptionClock(fakeClock)))
+			}()
+
+			fakeClock.BlockUntil(1)
+			fakeClock.Advance(3 * time.Second)
+
+			return <-errs
+		}
+
+		Convey("When the download is interrupted and the binary did not change", func() {
+
+			s := &flakyServer{first: binary, second: binary, etag: `"v1"`}
+			ts := httptest.NewServer(s)
+			defer ts.Close()
+
+			err := download(ts.URL, fmt.Sprintf("sha256:%x", sha256.Sum256(binary)))
+
+			Convey("Then the download should resume where it stopped", func() {
+				So(err, ShouldBeNil)
+				So(s.calls, ShouldEqual, 2)
+				So(s.ranges[0], ShouldBeEmpty)
+				So(s.ranges[1], ShouldStartWith, "bytes=")
+				So(s.ranges[1], ShouldNotEqual, "bytes=0-")
+				So(s.ifRange[1], ShouldEqual, `"v1"`)
+				data, _ := ioutil.ReadFile(dest)
+				So(data, ShouldResemble, binary)
+			})
+		})
+
+		Convey("When the download is interrupted and the binary changed", func() {
+
+			changed := bytes.Repeat([]byte("fedcba9876543210"), 4096)
+			s := &flakyServer{first: binary, second: changed, etag: `"v2"`}
+			ts := httptest.NewServer(s)
+			defer ts.Close()
+
+			err := download(ts.URL, fmt.Sprintf("sha256:%x", sha256.Sum256(changed)))
+
+			Convey("Then the download should restart from the beginning", func() {
+				So(err, ShouldBeNil)
+				So(s.calls, ShouldEqual, 2)
+				data, _ := ioutil.ReadFile(dest)
+				So(data, ShouldResemble, changed)
+			})
+		})
+	})
+}
+
+func TestBinary_Stall(t *testing.T) {
+
+	Convey("Given I have a server that stalls in the middle of a binary", t, func() {
+
+		dir, err := ioutil.TempDir("", "download")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir) // nolint: errcheck
+
+		dest := filepath.Join(dir, "enforcerd")
+
+		binary := bytes.Repeat([]byte("0123456789abcdef"), 4096)
+		s := &flakyServer{first: binary, second: binary, etag: `"v1"`, stall: true}
+		ts := httptest.NewServer(s)
+		defer ts.Close()
+
+		Convey("When I download it with an idle timeout", func() {
+
+			err := Binary(
+				context.Background(),
+				ts.URL,
+				dest,
+				0750,
+				fmt.Sprintf("sha256:%x", sha256.Sum256(binary)),
+				OptionIdleTimeout(100*time.Millisecond),
+				OptionRetry(retry.OptionBackoff(retry.NewConstantBackoff(0))),
+			)
+
+			Convey("Then the stalled attempt should fail and the next one resume", func() {
+				So(err, ShouldBeNil)
+				So(s.calls, ShouldEqual, 2)
+				So(s.ranges[1], ShouldStartWith, "bytes=")
+				So(s.ranges[1], ShouldNotEqual, "bytes=0-")
+				data, _ := ioutil.ReadFile(dest)
+				So(data, ShouldResemble, binary)
+			})
+		})
+
+		Convey("When the context is done while it stalls", func() {
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			err := Binary(ctx, ts.URL, dest, 0750, "", OptionIdleTimeout(0))
+
+			Convey("Then it should return", func() {
+				So(errors.Is(err, context.DeadlineExceeded), ShouldBeTrue)
+				files, _ := ioutil.ReadDir(dir)
+				So(len(files), ShouldEqual, 0)
+			})
+		})
+	})
+}
+
+func TestContentRangeStart(t *testing.T) {
+
+	Convey("Given I have various Content-Range values", t, func() {
+
+		Convey("Then the start should be parsed", func() {
+
+			start, ok := contentRangeStart("bytes 100-199/200")
+			So(ok, ShouldBeTrue)
+			So(start, ShouldEqual, 100)
+
+			start, ok = contentRangeStart("bytes 0-0/*")
+			So(ok, ShouldBeTrue)
+			So(start, ShouldEqual, 0)
+
+			_, ok = contentRangeStart("")
+			So(ok, ShouldBeFalse)
+
+			_, ok = contentRangeStart("bytes */200")
+			So(ok, ShouldBeFalse)
+		})
+	})
+}
@@ -0,0 +1,188 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package download
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"go.aporeto.io/addedeffect/retry"
+	"go.uber.org/zap"
+)
+
+// SignatureSuffix is appended to the URL of the manifest
+// and of the binaries to get their detached signatures.
+const SignatureSuffix = ".sig"
+
+// Sign returns the detached signature of the given data, to be served
+// next to it with SignatureSuffix. The signature is made over the SHA-512
+// digest of the data, and is encoded in base64. The key must be an
+// ed25519.PrivateKey or an *ecdsa.PrivateKey.
+func Sign(key crypto.Signer, data []byte) (string, error) {
+
+	sum := sha512.Sum512(data)
+
+	var opts crypto.SignerOpts
+	switch key.(type) {
+	case ed25519.PrivateKey:
+		opts = crypto.Hash(0)
+	case *ecdsa.PrivateKey:
+		opts = crypto.SHA512
+	default:
+		return "", fmt.Errorf("unsupported private key type: %T", key)
+	}
+
+	sig, err := key.Sign(rand.Reader, sum[:], opts)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(sig), nil
+}
+
+// verifySignature verifies the given base64 encoded signature of the given
+// SHA-512 digest. It succeeds if any of the given keys verifies it.
+// The keys must have been checked with config.checkPublicKeys.
+func verifySignature(keys []crypto.PublicKey, sum []byte, signature []byte) error {
+
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil {
+		return fmt.Errorf("unable to decode signature: %s", err)
+	}
+
+	for _, key := range keys {
+
+		switch k := key.(type) {
+
+		case ed25519.PublicKey:
+			if ed25519.Verify(k, sum, sig) {
+				return nil
+			}
+
+		case *ecdsa.PublicKey:
+			if ecdsa.VerifyASN1(k, sum, sig) {
+				return nil
+			}
+		}
+	}
+
+	return errors.New("invalid signature: no trusted key verifies it")
+}
+
+// checkPublicKeys returns an error if signatures must be verified but
+// no key is given, or if any of the given keys is not supported or is
+// invalid, so it is reported before downloading anything.
+func (c config) checkPublicKeys() error {
+
+	if !c.verifySignatures {
+		return nil
+	}
+
+	if len(c.publicKeys) == 0 {
+		return errors.New("no trusted public key given to verify signatures")
+	}
+
+	for i, key := range c.publicKeys {
+		switch k := key.(type) {
+
+		case ed25519.PublicKey:
+			if len(k) != ed25519.PublicKeySize {
+				return fmt.Errorf("invalid public key %d: ed25519 key must be %d bytes long", i, ed25519.PublicKeySize)
+			}
+
+		case *ecdsa.PublicKey:
+			if k == nil || k.Curve == nil || k.X == nil || k.Y == nil {
+				return fmt.Errorf("invalid public key %d: empty ecdsa key", i)
+			}
+
+		default:
+			return fmt.Errorf("unsupported public key type: %T", key)
+		}
+	}
+
+	return nil
+}
+
+// signatureURL returns the URL of the detached signature of the file
+// at the given URL. SignatureSuffix is appended to the path so the
+// query, if any, is kept.
+func signatureURL(rawURL string) (string, error) {
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	u.Path += SignatureSuffix
+	if u.RawPath != "" {
+		u.RawPath += SignatureSuffix
+	}
+
+	return u.String(), nil
+}
+
+// retrieveSignature downloads the detached signature at the given url.
+func retrieveSignature(ctx context.Context, url string, cfg config) ([]byte, error) {
+
+	resp, err := retry.Do(
+		ctx,
+		func(ctx context.Context) (*http.Response, error) {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+			if err != nil {
+				return nil, retry.Permanent(err)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				return nil, err
+			}
+
+			if resp.StatusCode == http.StatusNotFound {
+				resp.Body.Close() // nolint: errcheck
+				return nil, retry.Permanent(fmt.Errorf("unable to find signature: %s", resp.Status))
+			}
+
+			if resp.StatusCode != http.StatusOK {
+				resp.Body.Close() // nolint: errcheck
+				return nil, retry.WithRetryAfter(resp, fmt.Errorf("unable to download signature: %s", resp.Status))
+			}
+
+			return resp, nil
+		},
+		makeRetryOptions(cfg, func(a retry.Attempt) {
+			zap.L().Warn("Unable to download signature. retrying",
+				zap.Int("attempt", a.Number),
+				zap.Duration("retry-in", a.NextDelay),
+				zap.Error(a.Err),
+			)
+		})...,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close() // nolint: errcheck
+
+	return ioutil.ReadAll(resp.Body)
+}
@@ -0,0 +1,264 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package download
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type testFiles struct {
+	files map[string][]byte
+	lock  sync.Mutex
+}
+
+func (f *testFiles) set(path string, data []byte) {
+	f.lock.Lock()
+	f.files[path] = data
+	f.lock.Unlock()
+}
+
+func (f *testFiles) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	f.lock.Lock()
+	data, ok := f.files[r.URL.Path]
+	f.lock.Unlock()
+
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.Write(data) // nolint: errcheck
+}
+
+func TestSignature(t *testing.T) {
+
+	Convey("Given I have a server serving signed files", t, func() {
+
+		edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+		So(err, ShouldBeNil)
+
+		ecPriv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		So(err, ShouldBeNil)
+
+		otherPub, _, err := ed25519.GenerateKey(rand.Reader)
+		So(err, ShouldBeNil)
+
+		manifest := []byte(`{"enforcerd":{"latest":"1.0.0"}}`)
+		manifestSig, err := Sign(edPriv, manifest)
+		So(err, ShouldBeNil)
+
+		binary := []byte("#!/bin/sh\necho new\n")
+		binarySig, err := Sign(ecPriv, binary)
+		So(err, ShouldBeNil)
+
+		files := &testFiles{
+			files: map[string][]byte{
+				"/manifest":                       manifest,
+				"/manifest" + SignatureSuffix:     []byte(manifestSig),
+				"/enforcerd":                      binary,
+				"/enforcerd" + SignatureSuffix:    []byte(binarySig),
+				"/unsigned":                       binary,
+				"/badly-signed":                   binary,
+				"/badly-signed" + SignatureSuffix: []byte(manifestSig),
+			},
+		}
+		ts := httptest.NewServer(files)
+		defer ts.Close()
+
+		dir, err := ioutil.TempDir("", "download")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir) // nolint: errcheck
+
+		dest := filepath.Join(dir, "enforcerd")
+		So(ioutil.WriteFile(dest, []byte("old"), 0600), ShouldBeNil)
+
+		Convey("When I retrieve the manifest with the trusted key", func() {
+
+			m, err := RetrieveManifest(context.Background(), ts.URL+"/manifest", OptionPublicKeys(edPub))
+
+			Convey("Then it should be returned", func() {
+				So(err, ShouldBeNil)
+				So(m["enforcerd"].Latest, ShouldEqual, "1.0.0")
+			})
+		})
+
+		Convey("When I retrieve the manifest with several keys", func() {
+
+			_, err := RetrieveManifest(context.Background(), ts.URL+"/manifest", OptionPublicKeys(otherPub, &ecPriv.PublicKey, edPub))
+
+			Convey("Then it should be verified by any of them", func() {
+				So(err, ShouldBeNil)
+			})
+		})
+
+		Convey("When I retrieve the manifest from a URL with a query", func() {
+
+			m, err := RetrieveManifest(context.Background(), ts.URL+"/manifest?channel=stable", OptionPublicKeys(edPub))
+
+			Convey("Then the signature should be found and verified", func() {
+				So(err, ShouldBeNil)
+				So(m["enforcerd"].Latest, ShouldEqual, "1.0.0")
+			})
+		})
+
+		Convey("When I retrieve the manifest with an untrusted key", func() {
+
+			_, err := RetrieveManifest(context.Background(), ts.URL+"/manifest", OptionPublicKeys(otherPub))
+
+			Convey("Then it should fail", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "invalid signature: no trusted key verifies it")
+			})
+		})
+
+		Convey("When the manifest has been tampered with", func() {
+
+			files.set("/manifest", []byte(`{"enforcerd":{"latest":"6.6.6"}}`))
+			_, err := RetrieveManifest(context.Background(), ts.URL+"/manifest", OptionPublicKeys(edPub))
+
+			Convey("Then it should fail", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "invalid signature: no trusted key verifies it")
+			})
+		})
+
+		Convey("When I download a signed binary with the trusted key", func() {
+
+			err := Binary(context.Background(), ts.URL+"/enforcerd", dest, 0750, "", OptionPublicKeys(&ecPriv.PublicKey))
+
+			Convey("Then dest should be replaced", func() {
+				So(err, ShouldBeNil)
+				data, _ := ioutil.ReadFile(dest)
+				So(data, ShouldResemble, binary)
+			})
+		})
+
+		Convey("When I download a signed binary from a URL with a query", func() {
+
+			err := Binary(context.Background(), ts.URL+"/enforcerd?token=abc", dest, 0750, "", OptionPublicKeys(&ecPriv.PublicKey))
+
+			Convey("Then the signature should be found and verified", func() {
+				So(err, ShouldBeNil)
+				data, _ := ioutil.ReadFile(dest)
+				So(data, ShouldResemble, binary)
+			})
+		})
+
+		Convey("When I download a badly signed binary", func() {
+
+			err := Binary(context.Background(), ts.URL+"/badly-signed", dest, 0750, "", OptionPublicKeys(&ecPriv.PublicKey, edPub))
+
+			Convey("Then the previous binary should be left intact", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "invalid signature: no trusted key verifies it")
+				data, _ := ioutil.ReadFile(dest)
+				So(string(data), ShouldEqual, "old")
+				files, _ := ioutil.ReadDir(dir)
+				So(len(files), ShouldEqual, 1)
+			})
+		})
+
+		Convey("When I download an unsigned binary", func() {
+
+			err := Binary(context.Background(), ts.URL+"/unsigned", dest, 0750, "", OptionPublicKeys(edPub))
+
+			Convey("Then it should fail without retrying", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "unable to find signature: 404 Not Found")
+				data, _ := ioutil.ReadFile(dest)
+				So(string(data), ShouldEqual, "old")
+			})
+		})
+
+		Convey("When I use an unsupported key", func() {
+
+			_, err := RetrieveManifest(context.Background(), ts.URL+"/manifest", OptionPublicKeys(edPub, "not-a-key"))
+
+			Convey("Then it should fail", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "unsupported public key type: string")
+			})
+		})
+
+		Convey("When I retrieve the manifest with an empty list of keys", func() {
+
+			files.set("/manifest", []byte(`{"enforcerd":{"latest":"6.6.6"}}`))
+			_, err := RetrieveManifest(context.Background(), ts.URL+"/manifest", OptionPublicKeys())
+
+			Convey("Then it should fail", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "no trusted public key given to verify signatures")
+			})
+		})
+
+		Convey("When I download a binary with an empty list of keys", func() {
+
+			err := Binary(context.Background(), ts.URL+"/badly-signed", dest, 0750, "", OptionPublicKeys())
+
+			Convey("Then it should fail before downloading anything", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "no trusted public key given to verify signatures")
+				data, _ := ioutil.ReadFile(dest)
+				So(string(data), ShouldEqual, "old")
+			})
+		})
+
+		Convey("When I use a nil ecdsa key", func() {
+
+			var nilKey *ecdsa.PublicKey
+			_, err := RetrieveManifest(context.Background(), ts.URL+"/manifest", OptionPublicKeys(edPub, nilKey))
+
+			Convey("Then it should fail", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "invalid public key 1: empty ecdsa key")
+			})
+		})
+
+		Convey("When I use a truncated ed25519 key", func() {
+
+			err := Binary(context.Background(), ts.URL+"/enforcerd", dest, 0750, "", OptionPublicKeys(edPub[:16]))
+
+			Convey("Then it should fail", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "invalid public key 0: ed25519 key must be 32 bytes long")
+				data, _ := ioutil.ReadFile(dest)
+				So(string(data), ShouldEqual, "old")
+			})
+		})
+
+		Convey("When I download a binary with an unsupported key", func() {
+
+			err := Binary(context.Background(), ts.URL+"/enforcerd", dest, 0750, "", OptionPublicKeys(&ecPriv.PublicKey, 42))
+
+			Convey("Then it should fail before downloading anything", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "unsupported public key type: int")
+				data, _ := ioutil.ReadFile(dest)
+				So(string(data), ShouldEqual, "old")
+			})
+		})
+	})
+}
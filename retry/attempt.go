@@ -0,0 +1,62 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retry
+
+import (
+	"context"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+// An Attempt holds information about a failed attempt.
+type Attempt struct {
+
+	// Number is the number of the attempt, starting at 1.
+	Number int
+
+	// Err is the error returned by the attempt.
+	Err error
+
+	// Elapsed is the time spent since the first attempt started.
+	Elapsed time.Duration
+
+	// NextDelay is the time to wait before the next attempt.
+	NextDelay time.Duration
+}
+
+// traceAttempt logs an event describing the given attempt
+// in the span carried by the given context, if any.
+func traceAttempt(ctx context.Context, a Attempt) {
+
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return
+	}
+
+	fields := []log.Field{
+		log.String("event", "retry.attempt"),
+		log.Int("retry.attempt", a.Number),
+		log.String("retry.elapsed", a.Elapsed.String()),
+	}
+
+	if a.Err != nil {
+		fields = append(fields, log.Error(a.Err))
+	}
+
+	if a.NextDelay > 0 {
+		fields = append(fields, log.String("retry.next_delay", a.NextDelay.String()))
+	}
+
+	span.LogFields(fields...)
+}
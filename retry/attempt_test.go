@@ -0,0 +1,133 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDo_Hook(t *testing.T) {
+
+	Convey("Given I have a job that fails twice and a hook", t, func() {
+
+		var attempts []Attempt
+		var calls int
+
+		out, err := Do(
+			context.Background(),
+			func(context.Context) (string, error) {
+				calls++
+				if calls < 3 {
+					return "", errors.New("boom")
+				}
+				return "hello", nil
+			},
+			OptionBackoff(NewLinearBackoff(time.Millisecond, time.Millisecond)),
+			OptionHook(func(a Attempt) { attempts = append(attempts, a) }),
+		)
+
+		Convey("Then the hook should be called for each failed attempt", func() {
+			So(err, ShouldBeNil)
+			So(out, ShouldEqual, "hello")
+			So(len(attempts), ShouldEqual, 2)
+			So(attempts[0].Number, ShouldEqual, 1)
+			So(attempts[0].Err.Error(), ShouldEqual, "boom")
+			So(attempts[0].NextDelay, ShouldEqual, time.Millisecond)
+			So(attempts[1].Number, ShouldEqual, 2)
+			So(attempts[1].NextDelay, ShouldEqual, 2*time.Millisecond)
+			So(attempts[1].Elapsed, ShouldBeGreaterThanOrEqualTo, time.Millisecond)
+		})
+	})
+
+	Convey("Given I have a job that always fails and a hook", t, func() {
+
+		var attempts []Attempt
+
+		_, err := Do(
+			context.Background(),
+			func(context.Context) (string, error) { return "", errors.New("boom") },
+			OptionBackoff(NewConstantBackoff(time.Millisecond)),
+			OptionMaxAttempts(3),
+			OptionHook(func(a Attempt) { attempts = append(attempts, a) }),
+		)
+
+		Convey("Then the hook should not be called for the last attempt", func() {
+			So(err, ShouldHaveSameTypeAs, &ExhaustedError{})
+			So(len(attempts), ShouldEqual, 2)
+		})
+	})
+
+	Convey("Given I have a job that fails with a retry after error and a hook", t, func() {
+
+		var attempts []Attempt
+		var calls int
+
+		_, err := Do(
+			context.Background(),
+			func(context.Context) (string, error) {
+				calls++
+				if calls < 2 {
+					return "", RetryAfter(errors.New("slow down"), 2*time.Millisecond)
+				}
+				return "hello", nil
+			},
+			OptionHook(func(a Attempt) { attempts = append(attempts, a) }),
+		)
+
+		Convey("Then the hook should get the actual delay", func() {
+			So(err, ShouldBeNil)
+			So(len(attempts), ShouldEqual, 1)
+			So(attempts[0].NextDelay, ShouldEqual, 2*time.Millisecond)
+		})
+	})
+}
+
+func TestDo_Tracing(t *testing.T) {
+
+	Convey("Given I have a context carrying a span", t, func() {
+
+		span := mocktracer.New().StartSpan("test").(*mocktracer.MockSpan)
+		ctx := opentracing.ContextWithSpan(context.Background(), span)
+
+		var calls int
+		job := func(context.Context) (string, error) {
+			calls++
+			if calls < 2 {
+				return "", errors.New("boom")
+			}
+			return "hello", nil
+		}
+
+		Convey("When I call Do with tracing enabled", func() {
+
+			_, err := Do(ctx, job, OptionBackoff(NewConstantBackoff(time.Millisecond)), OptionTracing())
+
+			Convey("Then an event should be logged for each attempt", func() {
+				So(err, ShouldBeNil)
+				logs := span.Logs()
+				So(len(logs), ShouldEqual, 2)
+				So(logs[0].Fields[0].Key, ShouldEqual, "event")
+				So(logs[0].Fields[0].ValueString, ShouldEqual, "retry.attempt")
+				So(logs[0].Fields[1].ValueString, ShouldEqual, "1")
+				So(logs[0].Fields[len(logs[0].Fields)-1].Key, ShouldEqual, "retry.next_delay")
+				So(logs[1].Fields[1].ValueString, ShouldEqual, "2")
+				So(len(logs[1].Fields), ShouldEqual, 3)
+			})
+		})
+
+		Convey("When I call Do without tracing enabled", func() {
+
+			_, err := Do(ctx, job, OptionBackoff(NewConstantBackoff(time.Millisecond)))
+
+			Convey("Then no event should be logged", func() {
+				So(err, ShouldBeNil)
+				So(span.Logs(), ShouldBeEmpty)
+			})
+		})
+	})
+}
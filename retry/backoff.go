@@ -0,0 +1,142 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retry
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// A Backoff computes the time to wait between two attempts.
+// Implementations must be safe for concurrent use.
+type Backoff interface {
+
+	// Delay returns the time to wait after the given failed
+	// attempt, starting at 1. previous is the delay returned
+	// for the previous attempt, or 0 for the first one.
+	Delay(attempt int, previous time.Duration) time.Duration
+}
+
+type constantBackoff struct {
+	delay time.Duration
+}
+
+// NewConstantBackoff returns a Backoff always waiting the given delay.
+func NewConstantBackoff(delay time.Duration) Backoff {
+	return constantBackoff{delay: delay}
+}
+
+func (b constantBackoff) Delay(int, time.Duration) time.Duration {
+	return b.delay
+}
+
+type linearBackoff struct {
+	initial time.Duration
+	step    time.Duration
+}
+
+// NewLinearBackoff returns a Backoff waiting initial after the first
+// attempt, then adding step to the delay after each attempt.
+func NewLinearBackoff(initial time.Duration, step time.Duration) Backoff {
+	return linearBackoff{initial: initial, step: step}
+}
+
+func (b linearBackoff) Delay(attempt int, _ time.Duration) time.Duration {
+
+	d := float64(b.initial) + float64(b.step)*float64(attempt-1)
+	if d > math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+
+	return time.Duration(d)
+}
+
+type exponentialBackoff struct {
+	initial time.Duration
+	factor  float64
+}
+
+// NewExponentialBackoff returns a Backoff waiting initial after the
+// first attempt, then multiplying the delay by factor after each attempt.
+func NewExponentialBackoff(initial time.Duration, factor float64) Backoff {
+	return exponentialBackoff{initial: initial, factor: factor}
+}
+
+func (b exponentialBackoff) Delay(attempt int, _ time.Duration) time.Duration {
+
+	d := float64(b.initial) * math.Pow(b.factor, float64(attempt-1))
+	if d > math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+
+	return time.Duration(d)
+}
+
+type decorrelatedJitterBackoff struct {
+	base time.Duration
+	max  time.Duration
+}
+
+// NewDecorrelatedJitterBackoff returns a Backoff waiting a random delay
+// between base and three times the previous delay, never exceeding max.
+// This spreads the attempts of concurrent callers over time.
+func NewDecorrelatedJitterBackoff(base time.Duration, max time.Duration) Backoff {
+	return decorrelatedJitterBackoff{base: base, max: max}
+}
+
+func (b decorrelatedJitterBackoff) Delay(_ int, previous time.Duration) time.Duration {
+
+	if previous < b.base {
+		previous = b.base
+	}
+
+	upper := 3 * previous
+	if upper <= b.base {
+		return b.base
+	}
+
+	d := b.base + time.Duration(rand.Int63n(int64(upper-b.base))) // #nosec
+	if b.max > 0 && d > b.max {
+		return b.max
+	}
+
+	return d
+}
+
+type boundedBackoff struct {
+	backoff Backoff
+	min     time.Duration
+	max     time.Duration
+}
+
+// NewBoundedBackoff returns a Backoff that caps the delays
+// of the given Backoff between min and max. If max is 0,
+// the delays are not capped upwards.
+func NewBoundedBackoff(backoff Backoff, min time.Duration, max time.Duration) Backoff {
+	return boundedBackoff{backoff: backoff, min: min, max: max}
+}
+
+func (b boundedBackoff) Delay(attempt int, previous time.Duration) time.Duration {
+
+	d := b.backoff.Delay(attempt, previous)
+
+	if d < b.min {
+		return b.min
+	}
+
+	if b.max > 0 && d > b.max {
+		return b.max
+	}
+
+	return d
+}
@@ -0,0 +1,77 @@
+package retry
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBackoffs(t *testing.T) {
+
+	Convey("Given I have a constant backoff", t, func() {
+
+		b := NewConstantBackoff(time.Second)
+
+		Convey("Then the delays should be constant", func() {
+			So(b.Delay(1, 0), ShouldEqual, time.Second)
+			So(b.Delay(10, time.Second), ShouldEqual, time.Second)
+		})
+	})
+
+	Convey("Given I have a linear backoff", t, func() {
+
+		b := NewLinearBackoff(time.Second, 2*time.Second)
+
+		Convey("Then the delays should grow linearly", func() {
+			So(b.Delay(1, 0), ShouldEqual, time.Second)
+			So(b.Delay(2, 0), ShouldEqual, 3*time.Second)
+			So(b.Delay(3, 0), ShouldEqual, 5*time.Second)
+		})
+	})
+
+	Convey("Given I have an exponential backoff", t, func() {
+
+		b := NewExponentialBackoff(time.Second, 2)
+
+		Convey("Then the delays should grow exponentially", func() {
+			So(b.Delay(1, 0), ShouldEqual, time.Second)
+			So(b.Delay(2, 0), ShouldEqual, 2*time.Second)
+			So(b.Delay(4, 0), ShouldEqual, 8*time.Second)
+		})
+
+		Convey("Then the delays should not overflow", func() {
+			So(b.Delay(1000, 0), ShouldBeGreaterThan, 0)
+		})
+	})
+
+	Convey("Given I have a decorrelated jitter backoff", t, func() {
+
+		b := NewDecorrelatedJitterBackoff(time.Second, 10*time.Second)
+
+		Convey("Then the delays should be within bounds", func() {
+			var previous time.Duration
+			for i := 1; i < 100; i++ {
+				d := b.Delay(i, previous)
+				So(d, ShouldBeGreaterThanOrEqualTo, time.Second)
+				So(d, ShouldBeLessThanOrEqualTo, 10*time.Second)
+				if previous > 0 {
+					So(d, ShouldBeLessThanOrEqualTo, 3*previous)
+				}
+				previous = d
+			}
+		})
+	})
+
+	Convey("Given I have a bounded backoff", t, func() {
+
+		b := NewBoundedBackoff(NewExponentialBackoff(time.Second, 2), 2*time.Second, 5*time.Second)
+
+		Convey("Then the delays should be capped", func() {
+			So(b.Delay(1, 0), ShouldEqual, 2*time.Second)
+			So(b.Delay(2, 0), ShouldEqual, 2*time.Second)
+			So(b.Delay(3, 0), ShouldEqual, 4*time.Second)
+			So(b.Delay(4, 0), ShouldEqual, 5*time.Second)
+		})
+	})
+}
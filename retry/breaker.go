@@ -0,0 +1,263 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retry
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned when an attempt is rejected
+// because the circuit breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// A State represents the state of a CircuitBreaker.
+type State int
+
+// Various values of State.
+const (
+	// StateClosed lets all attempts through.
+	StateClosed State = iota
+
+	// StateOpen rejects all attempts until the cool-down is over.
+	StateOpen
+
+	// StateHalfOpen lets a single probing attempt through
+	// to decide if the circuit can be closed again.
+	StateHalfOpen
+)
+
+func (s State) String() string {
+
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+const (
+	defaultFailureThreshold = 5
+	defaultSuccessThreshold = 1
+	defaultCoolDown         = 30 * time.Second
+)
+
+type breakerConfig struct {
+	failureThreshold int
+	successThreshold int
+	coolDown         time.Duration
+	onStateChange    func(from State, to State)
+	clock            Clock
+}
+
+func newBreakerConfig() breakerConfig {
+	return breakerConfig{
+		failureThreshold: defaultFailureThreshold,
+		successThreshold: defaultSuccessThreshold,
+		coolDown:         defaultCoolDown,
+		clock:            SystemClock(),
+	}
+}
+
+// A BreakerOption can be used to configure a CircuitBreaker.
+type BreakerOption func(*breakerConfig)
+
+// BreakerOptionFailureThreshold sets the number of consecutive
+// failures after which the circuit opens. Default is 5.
+func BreakerOptionFailureThreshold(n int) BreakerOption {
+	return func(c *breakerConfig) {
+		c.failureThreshold = n
+	}
+}
+
+// BreakerOptionSuccessThreshold sets the number of consecutive
+// successful probes needed to close a half-open circuit. Default is 1.
+func BreakerOptionSuccessThreshold(n int) BreakerOption {
+	return func(c *breakerConfig) {
+		c.successThreshold = n
+	}
+}
+
+// BreakerOptionCoolDown sets how long the circuit stays open
+// before letting a probe through. Default is 30s.
+func BreakerOptionCoolDown(d time.Duration) BreakerOption {
+	return func(c *breakerConfig) {
+		c.coolDown = d
+	}
+}
+
+// BreakerOptionOnStateChange sets a function called every time
+// the circuit changes state. It is called synchronously, and
+// must not use the CircuitBreaker.
+func BreakerOptionOnStateChange(f func(from State, to State)) BreakerOption {
+	return func(c *breakerConfig) {
+		c.onStateChange = f
+	}
+}
+
+// BreakerOptionClock sets the Clock used to
+// measure the cool-down. Default is SystemClock.
+func BreakerOptionClock(clock Clock) BreakerOption {
+	return func(c *breakerConfig) {
+		c.clock = clock
+	}
+}
+
+// A CircuitBreaker stops attempts to reach a dependency that keeps failing.
+// It opens after a number of consecutive failures, rejects all attempts
+// during a cool-down, then lets a single probe through. If the probe
+// succeeds, the circuit closes, otherwise it opens again.
+//
+// A CircuitBreaker is safe for concurrent use and is meant to be
+// shared among all the callers of the same dependency, either directly
+// or by giving it to Do using OptionCircuitBreaker.
+type CircuitBreaker struct {
+	cfg       breakerConfig
+	state     State
+	failures  int
+	successes int
+	probing   bool
+	openedAt  time.Time
+	lock      sync.Mutex
+}
+
+// NewCircuitBreaker returns a new closed CircuitBreaker
+// configured with the given options.
+func NewCircuitBreaker(options ...BreakerOption) *CircuitBreaker {
+
+	cfg := newBreakerConfig()
+	for _, opt := range options {
+		opt(&cfg)
+	}
+
+	return &CircuitBreaker{
+		cfg: cfg,
+	}
+}
+
+// State returns the current state of the circuit.
+func (b *CircuitBreaker) State() State {
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.state == StateOpen && b.cfg.clock.Now().Sub(b.openedAt) >= b.cfg.coolDown {
+		return StateHalfOpen
+	}
+
+	return b.state
+}
+
+// Allow returns ErrCircuitOpen if an attempt must not be made.
+// Otherwise, the caller must report the outcome of the attempt
+// using Success or Failure.
+func (b *CircuitBreaker) Allow() error {
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	switch b.state {
+
+	case StateOpen:
+		if b.cfg.clock.Now().Sub(b.openedAt) < b.cfg.coolDown {
+			return ErrCircuitOpen
+		}
+		b.setState(StateHalfOpen)
+		b.probing = true
+		return nil
+
+	case StateHalfOpen:
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+		return nil
+	}
+
+	return nil
+}
+
+// Success reports a successful attempt.
+func (b *CircuitBreaker) Success() {
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	switch b.state {
+
+	case StateClosed:
+		b.failures = 0
+
+	case StateHalfOpen:
+		b.probing = false
+		b.successes++
+		if b.successes >= b.cfg.successThreshold {
+			b.setState(StateClosed)
+		}
+	}
+}
+
+// Failure reports a failed attempt.
+func (b *CircuitBreaker) Failure() {
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	switch b.state {
+
+	case StateClosed:
+		b.failures++
+		if b.failures >= b.cfg.failureThreshold {
+			b.setState(StateOpen)
+		}
+
+	case StateHalfOpen:
+		b.setState(StateOpen)
+	}
+}
+
+// release ends a probe without counting it as a success or a failure.
+func (b *CircuitBreaker) release() {
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.probing = false
+}
+
+// setState must be called with the lock held.
+func (b *CircuitBreaker) setState(state State) {
+
+	if b.state == state {
+		return
+	}
+
+	from := b.state
+	b.state = state
+	b.failures = 0
+	b.successes = 0
+	b.probing = false
+
+	if state == StateOpen {
+		b.openedAt = b.cfg.clock.Now()
+	}
+
+	if b.cfg.onStateChange != nil {
+		b.cfg.onStateChange(from, state)
+	}
+}
@@ -0,0 +1,160 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+
+	Convey("Given I have a circuit breaker", t, func() {
+
+		var transitions []string
+		clock := NewFakeClock(time.Now())
+
+		b := NewCircuitBreaker(
+			BreakerOptionFailureThreshold(3),
+			BreakerOptionSuccessThreshold(2),
+			BreakerOptionCoolDown(10*time.Second),
+			BreakerOptionOnStateChange(func(from State, to State) {
+				transitions = append(transitions, from.String()+"->"+to.String())
+			}),
+			BreakerOptionClock(clock),
+		)
+
+		Convey("Then it should be closed", func() {
+			So(b.State(), ShouldEqual, StateClosed)
+			So(b.Allow(), ShouldBeNil)
+		})
+
+		Convey("When I report less failures than the threshold", func() {
+
+			b.Failure()
+			b.Failure()
+			b.Success()
+			b.Failure()
+			b.Failure()
+
+			Convey("Then it should still be closed", func() {
+				So(b.State(), ShouldEqual, StateClosed)
+				So(transitions, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When I report enough consecutive failures", func() {
+
+			b.Failure()
+			b.Failure()
+			b.Failure()
+
+			Convey("Then it should be open", func() {
+				So(b.State(), ShouldEqual, StateOpen)
+				So(b.Allow(), ShouldEqual, ErrCircuitOpen)
+				So(transitions, ShouldResemble, []string{"closed->open"})
+			})
+
+			Convey("When the cool-down is over", func() {
+
+				clock.Advance(10 * time.Second)
+
+				Convey("Then a single probe should be allowed", func() {
+					So(b.State(), ShouldEqual, StateHalfOpen)
+					So(b.Allow(), ShouldBeNil)
+					So(b.Allow(), ShouldEqual, ErrCircuitOpen)
+					So(transitions, ShouldResemble, []string{"closed->open", "open->half-open"})
+				})
+
+				Convey("When the probe fails", func() {
+
+					So(b.Allow(), ShouldBeNil)
+					b.Failure()
+
+					Convey("Then it should be open again", func() {
+						So(b.State(), ShouldEqual, StateOpen)
+						So(b.Allow(), ShouldEqual, ErrCircuitOpen)
+						So(transitions, ShouldResemble, []string{"closed->open", "open->half-open", "half-open->open"})
+					})
+				})
+
+				Convey("When enough probes succeed", func() {
+
+					So(b.Allow(), ShouldBeNil)
+					b.Success()
+					So(b.State(), ShouldEqual, StateHalfOpen)
+					So(b.Allow(), ShouldBeNil)
+					b.Success()
+
+					Convey("Then it should be closed", func() {
+						So(b.State(), ShouldEqual, StateClosed)
+						So(b.Allow(), ShouldBeNil)
+						So(transitions, ShouldResemble, []string{"closed->open", "open->half-open", "half-open->closed"})
+					})
+				})
+			})
+		})
+	})
+}
+
+func TestDo_CircuitBreaker(t *testing.T) {
+
+	Convey("Given I have a circuit breaker shared by several retries", t, func() {
+
+		b := NewCircuitBreaker(BreakerOptionFailureThreshold(3))
+
+		var attempts int
+		job := func(context.Context) (string, error) {
+			attempts++
+			return "", errors.New("boom")
+		}
+
+		Convey("When the first retry fails enough to open the circuit", func() {
+
+			_, err := Do(
+				context.Background(),
+				job,
+				OptionBackoff(NewConstantBackoff(time.Millisecond)),
+				OptionCircuitBreaker(b),
+			)
+
+			Convey("Then it should fail fast with ErrCircuitOpen", func() {
+				So(errors.Is(err, ErrCircuitOpen), ShouldBeTrue)
+				So(attempts, ShouldEqual, 3)
+				So(b.State(), ShouldEqual, StateOpen)
+			})
+
+			Convey("When I start another retry", func() {
+
+				attempts = 0
+				_, err := Do(
+					context.Background(),
+					job,
+					OptionCircuitBreaker(b),
+				)
+
+				Convey("Then the job should not be called", func() {
+					So(errors.Is(err, ErrCircuitOpen), ShouldBeTrue)
+					So(attempts, ShouldEqual, 0)
+				})
+			})
+		})
+
+		Convey("When a retry gets a permanent error", func() {
+
+			_, err := Do(
+				context.Background(),
+				func(context.Context) (string, error) { return "", Permanent(errors.New("nope")) },
+				OptionCircuitBreaker(b),
+			)
+
+			Convey("Then it should not count as a failure", func() {
+				So(err.Error(), ShouldEqual, "nope")
+				So(b.State(), ShouldEqual, StateClosed)
+				So(b.failures, ShouldEqual, 0)
+			})
+		})
+	})
+}
@@ -0,0 +1,119 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retry
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// ErrBudgetExhausted is returned, wrapping the error of the last attempt,
+// when a retry is not allowed because the Budget is exhausted.
+var ErrBudgetExhausted = errors.New("retry budget exhausted")
+
+// A Budget is a token bucket limiting the number of retries per second.
+// Every retry takes a token from the bucket, which is refilled at a
+// constant rate, up to a maximum burst. First attempts are never limited.
+//
+// A Budget is safe for concurrent use and is meant to be shared among
+// all the retries of a process, using OptionBudget or OptionBudgetWait,
+// so that a widespread outage does not multiply the load on the backends.
+type Budget struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	clock  Clock
+	lock   sync.Mutex
+}
+
+// A BudgetOption can be used to configure a Budget.
+type BudgetOption func(*Budget)
+
+// BudgetOptionClock sets the Clock used to
+// refill the budget. Default is SystemClock.
+func BudgetOptionClock(clock Clock) BudgetOption {
+	return func(b *Budget) {
+		b.clock = clock
+	}
+}
+
+// NewBudget returns a new full Budget allowing the given
+// number of retries per second, with the given burst.
+func NewBudget(rate float64, burst int, options ...BudgetOption) *Budget {
+
+	b := &Budget{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		clock:  SystemClock(),
+	}
+
+	for _, opt := range options {
+		opt(b)
+	}
+
+	return b
+}
+
+// Allow takes a token from the budget and returns true
+// if one is available. Otherwise it returns false.
+func (b *Budget) Allow() bool {
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.refill()
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+
+	return true
+}
+
+// Reserve takes a token from the budget, even if none is available,
+// and returns how long to wait before the token can be used.
+func (b *Budget) Reserve() time.Duration {
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.refill()
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+
+	if b.rate <= 0 {
+		return time.Duration(math.MaxInt64)
+	}
+
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// refill must be called with the lock held.
+func (b *Budget) refill() {
+
+	now := b.clock.Now()
+
+	if !b.last.IsZero() {
+		b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	}
+
+	b.last = now
+}
@@ -0,0 +1,132 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBudget(t *testing.T) {
+
+	Convey("Given I have a budget of 2 retries per second with a burst of 3", t, func() {
+
+		clock := NewFakeClock(time.Now())
+		b := NewBudget(2, 3, BudgetOptionClock(clock))
+
+		Convey("Then the burst should be allowed", func() {
+			So(b.Allow(), ShouldBeTrue)
+			So(b.Allow(), ShouldBeTrue)
+			So(b.Allow(), ShouldBeTrue)
+			So(b.Allow(), ShouldBeFalse)
+		})
+
+		Convey("When the budget is exhausted and time passes", func() {
+
+			b.Allow()
+			b.Allow()
+			b.Allow()
+
+			clock.Advance(500 * time.Millisecond)
+
+			Convey("Then it should be refilled at the given rate", func() {
+				So(b.Allow(), ShouldBeTrue)
+				So(b.Allow(), ShouldBeFalse)
+			})
+		})
+
+		Convey("When a lot of time passes", func() {
+
+			clock.Advance(time.Hour)
+
+			Convey("Then it should not be refilled above the burst", func() {
+				So(b.Allow(), ShouldBeTrue)
+				So(b.Allow(), ShouldBeTrue)
+				So(b.Allow(), ShouldBeTrue)
+				So(b.Allow(), ShouldBeFalse)
+			})
+		})
+
+		Convey("When I reserve tokens", func() {
+
+			waits := []time.Duration{b.Reserve(), b.Reserve(), b.Reserve(), b.Reserve(), b.Reserve()}
+
+			Convey("Then the waits should grow once the budget is exhausted", func() {
+				So(waits, ShouldResemble, []time.Duration{0, 0, 0, 500 * time.Millisecond, time.Second})
+				So(b.Allow(), ShouldBeFalse)
+			})
+		})
+	})
+}
+
+func TestDo_Budget(t *testing.T) {
+
+	Convey("Given I have a budget shared by several retries", t, func() {
+
+		b := NewBudget(100, 2, BudgetOptionClock(NewFakeClock(time.Now())))
+
+		var calls int
+		job := func(context.Context) (string, error) {
+			calls++
+			return "", errors.New("boom")
+		}
+
+		Convey("When I call Do until the budget is exhausted", func() {
+
+			_, err := Do(
+				context.Background(),
+				job,
+				OptionBackoff(NewConstantBackoff(time.Millisecond)),
+				OptionBudget(b),
+			)
+
+			Convey("Then it should fail fast", func() {
+				So(errors.Is(err, ErrBudgetExhausted), ShouldBeTrue)
+				So(err.Error(), ShouldEqual, "retry budget exhausted: boom")
+				So(calls, ShouldEqual, 3)
+			})
+
+			Convey("When I call Do again", func() {
+
+				calls = 0
+				_, err := Do(
+					context.Background(),
+					job,
+					OptionBackoff(NewConstantBackoff(time.Millisecond)),
+					OptionBudget(b),
+				)
+
+				Convey("Then only the first attempt should be made", func() {
+					So(errors.Is(err, ErrBudgetExhausted), ShouldBeTrue)
+					So(calls, ShouldEqual, 1)
+				})
+			})
+		})
+
+		Convey("When I call Do waiting for the budget", func() {
+
+			var delays []time.Duration
+			_, err := Do(
+				context.Background(),
+				job,
+				OptionBackoff(NewConstantBackoff(time.Millisecond)),
+				OptionBudgetWait(b),
+				OptionMaxAttempts(5),
+				OptionHook(func(a Attempt) { delays = append(delays, a.NextDelay) }),
+			)
+
+			Convey("Then the retries should wait for the budget", func() {
+				So(err, ShouldHaveSameTypeAs, &ExhaustedError{})
+				So(calls, ShouldEqual, 5)
+				So(delays, ShouldResemble, []time.Duration{
+					time.Millisecond,
+					time.Millisecond,
+					10 * time.Millisecond,
+					20 * time.Millisecond,
+				})
+			})
+		})
+	})
+}
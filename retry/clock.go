@@ -0,0 +1,216 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retry
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// A Clock provides the time to the retry procedures,
+// so tests can control it using a FakeClock.
+type Clock interface {
+
+	// Now returns the current time.
+	Now() time.Time
+
+	// After returns a channel receiving the
+	// current time once the given duration elapsed.
+	After(d time.Duration) <-chan time.Time
+
+	// NewTimer returns a new Timer firing
+	// once the given duration elapsed.
+	NewTimer(d time.Duration) Timer
+}
+
+// A Timer is a timer created by a Clock.
+type Timer interface {
+
+	// C returns the channel receiving the time when the timer fires.
+	C() <-chan time.Time
+
+	// Stop prevents the timer from firing. It returns
+	// false if the timer already fired or was stopped.
+	Stop() bool
+
+	// Reset changes the timer to fire after the given duration. It
+	// returns false if the timer already fired or was stopped.
+	Reset(d time.Duration) bool
+}
+
+// SystemClock returns a Clock using the time package.
+func SystemClock() Clock {
+	return systemClock{}
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (systemClock) NewTimer(d time.Duration) Timer         { return systemTimer{time.NewTimer(d)} }
+
+type systemTimer struct {
+	*time.Timer
+}
+
+func (t systemTimer) C() <-chan time.Time { return t.Timer.C }
+
+// A FakeClock is a Clock whose time only changes when
+// Advance is called. It is meant to be used in tests.
+type FakeClock struct {
+	now    time.Time
+	timers []*fakeTimer
+	lock   sync.Mutex
+	cond   *sync.Cond
+}
+
+// NewFakeClock returns a new FakeClock set to the given time.
+func NewFakeClock(now time.Time) *FakeClock {
+
+	c := &FakeClock{
+		now: now,
+	}
+	c.cond = sync.NewCond(&c.lock)
+
+	return c
+}
+
+// Now returns the current time of the clock.
+func (c *FakeClock) Now() time.Time {
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.now
+}
+
+// After returns a channel receiving the time of the clock
+// once it has been advanced by the given duration.
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+// NewTimer returns a new Timer firing once the
+// clock has been advanced by the given duration.
+func (c *FakeClock) NewTimer(d time.Duration) Timer {
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	t := &fakeTimer{
+		clock: c,
+		c:     make(chan time.Time, 1),
+	}
+	c.schedule(t, d)
+
+	return t
+}
+
+// Advance moves the time of the clock forward by
+// the given duration, firing the expired timers.
+func (c *FakeClock) Advance(d time.Duration) {
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.now = c.now.Add(d)
+
+	var pending []*fakeTimer
+	for _, t := range c.timers {
+		if t.deadline.After(c.now) {
+			pending = append(pending, t)
+			continue
+		}
+		t.fire(c.now)
+	}
+
+	c.timers = pending
+}
+
+// BlockUntil blocks until at least the given
+// number of timers are waiting for the clock.
+func (c *FakeClock) BlockUntil(n int) {
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for len(c.timers) < n {
+		c.cond.Wait()
+	}
+}
+
+// schedule must be called with the lock held.
+func (c *FakeClock) schedule(t *fakeTimer, d time.Duration) {
+
+	if d <= 0 {
+		t.fire(c.now)
+		return
+	}
+
+	t.deadline = c.now.Add(d)
+	c.timers = append(c.timers, t)
+	sort.SliceStable(c.timers, func(i, j int) bool { return c.timers[i].deadline.Before(c.timers[j].deadline) })
+
+	c.cond.Broadcast()
+}
+
+// unschedule must be called with the lock held.
+func (c *FakeClock) unschedule(t *fakeTimer) bool {
+
+	for i, pending := range c.timers {
+		if pending == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+type fakeTimer struct {
+	clock    *FakeClock
+	c        chan time.Time
+	deadline time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+
+	t.clock.lock.Lock()
+	defer t.clock.lock.Unlock()
+
+	return t.clock.unschedule(t)
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+
+	t.clock.lock.Lock()
+	defer t.clock.lock.Unlock()
+
+	active := t.clock.unschedule(t)
+	t.clock.schedule(t, d)
+
+	return active
+}
+
+// fire does not block if the previous time was not received.
+func (t *fakeTimer) fire(now time.Time) {
+
+	select {
+	case t.c <- now:
+	default:
+	}
+}
@@ -0,0 +1,149 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFakeClock(t *testing.T) {
+
+	Convey("Given I have a fake clock", t, func() {
+
+		now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		clock := NewFakeClock(now)
+
+		Convey("Then its time should not change", func() {
+			So(clock.Now(), ShouldEqual, now)
+		})
+
+		Convey("When I create timers and advance the clock", func() {
+
+			t1 := clock.NewTimer(time.Second)
+			t2 := clock.NewTimer(2 * time.Second)
+			t3 := clock.NewTimer(3 * time.Second)
+			So(t3.Stop(), ShouldBeTrue)
+
+			clock.Advance(time.Second)
+
+			Convey("Then only the expired timers should fire", func() {
+				So(clock.Now(), ShouldEqual, now.Add(time.Second))
+				So(<-t1.C(), ShouldEqual, now.Add(time.Second))
+				So(len(t2.C()), ShouldEqual, 0)
+				So(t1.Stop(), ShouldBeFalse)
+			})
+
+			Convey("When I reset a timer and advance the clock again", func() {
+
+				So(t2.Reset(3*time.Second), ShouldBeTrue)
+				clock.Advance(2 * time.Second)
+
+				Convey("Then it should not have fired yet", func() {
+					So(len(t2.C()), ShouldEqual, 0)
+					So(len(t3.C()), ShouldEqual, 0)
+				})
+
+				Convey("When I advance the clock enough", func() {
+
+					clock.Advance(time.Second)
+
+					Convey("Then it should have fired", func() {
+						So(<-t2.C(), ShouldEqual, now.Add(4*time.Second))
+					})
+				})
+			})
+		})
+
+		Convey("When I wait on the clock from another goroutine", func() {
+
+			done := make(chan struct{})
+			go func() {
+				<-clock.After(time.Minute)
+				close(done)
+			}()
+
+			clock.BlockUntil(1)
+			clock.Advance(time.Minute)
+
+			Convey("Then the waiter should be released", func() {
+				select {
+				case <-done:
+				case <-time.After(time.Second):
+					So("waiter was not released", ShouldBeEmpty)
+				}
+			})
+		})
+	})
+}
+
+func TestDo_Clock(t *testing.T) {
+
+	Convey("Given I have a job that fails twice and a fake clock", t, func() {
+
+		clock := NewFakeClock(time.Now())
+
+		var calls int
+		type result struct {
+			out interface{}
+			err error
+		}
+		results := make(chan result)
+
+		go func() {
+			out, err := RetryWithOptions(
+				context.Background(),
+				func() (interface{}, error) {
+					calls++
+					if calls < 3 {
+						return nil, errors.New("boom")
+					}
+					return "hello", nil
+				},
+				nil,
+				OptionClock(clock),
+			)
+			results <- result{out: out, err: err}
+		}()
+
+		clock.BlockUntil(1)
+		clock.Advance(3 * time.Second)
+		clock.BlockUntil(1)
+		clock.Advance(3 * time.Second)
+
+		r := <-results
+
+		Convey("Then it should succeed without waiting for real", func() {
+			So(r.err, ShouldBeNil)
+			So(r.out, ShouldEqual, "hello")
+			So(calls, ShouldEqual, 3)
+		})
+	})
+
+	Convey("Given I have a job that always fails, a fake clock and a max elapsed time", t, func() {
+
+		clock := NewFakeClock(time.Now())
+
+		var calls int
+
+		_, err := Do(
+			context.Background(),
+			func(context.Context) (string, error) {
+				calls++
+				clock.Advance(time.Second)
+				return "", errors.New("boom")
+			},
+			OptionBackoff(NewConstantBackoff(0)),
+			OptionMaxElapsedTime(5*time.Second),
+			OptionClock(clock),
+		)
+
+		Convey("Then the elapsed time should be measured with the clock", func() {
+			So(err, ShouldHaveSameTypeAs, &ExhaustedError{})
+			So(err.(*ExhaustedError).Elapsed, ShouldEqual, 6*time.Second)
+			So(calls, ShouldEqual, 6)
+		})
+	})
+}
@@ -0,0 +1,79 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retry
+
+import (
+	"fmt"
+	"time"
+)
+
+// An ExhaustedError is returned when the maximum number
+// of attempts or the maximum elapsed time is reached.
+// It wraps the error returned by the last attempt.
+type ExhaustedError struct {
+	Attempts int
+	Elapsed  time.Duration
+	Err      error
+}
+
+func (e *ExhaustedError) Error() string {
+	return fmt.Sprintf("retry exhausted after %d attempts in %s: %s", e.Attempts, e.Elapsed, e.Err)
+}
+
+// Unwrap returns the error returned by the last attempt.
+func (e *ExhaustedError) Unwrap() error {
+	return e.Err
+}
+
+// A PermanentError wraps an error that must not be retried.
+// Use Permanent to create one.
+type PermanentError struct {
+	Err error
+}
+
+// Permanent wraps the given error so the retry procedure stops
+// immediately and returns it, without calling the retry function.
+func Permanent(err error) error {
+	return &PermanentError{Err: err}
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// A RetryAfterError wraps an error that must be retried after
+// the given delay instead of the one computed by the backoff.
+// Use RetryAfter to create one.
+type RetryAfterError struct {
+	Err   error
+	Delay time.Duration
+}
+
+// RetryAfter wraps the given error so the next attempt
+// happens after the given delay.
+func RetryAfter(err error, delay time.Duration) error {
+	return &RetryAfterError{Err: err, Delay: delay}
+}
+
+func (e *RetryAfterError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *RetryAfterError) Unwrap() error {
+	return e.Err
+}
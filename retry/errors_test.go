@@ -0,0 +1,171 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPermanent(t *testing.T) {
+
+	Convey("Given I have a job returning a permanent error", t, func() {
+
+		jobErr := errors.New("boom")
+
+		var attempts, retried int
+		out, err := Do(
+			context.Background(),
+			func(context.Context) (string, error) {
+				attempts++
+				return "", fmt.Errorf("wrapped: %w", Permanent(jobErr))
+			},
+			OptionBackoff(NewConstantBackoff(time.Millisecond)),
+			OptionRetryFunc(func(error) error { retried++; return nil }),
+		)
+
+		Convey("Then the retry should stop immediately", func() {
+			So(out, ShouldBeEmpty)
+			So(attempts, ShouldEqual, 1)
+			So(retried, ShouldEqual, 0)
+		})
+
+		Convey("Then the error should be returned", func() {
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "wrapped: boom")
+			So(errors.Is(err, jobErr), ShouldBeTrue)
+		})
+	})
+}
+
+func TestRetryAfter(t *testing.T) {
+
+	Convey("Given I have a job returning a retry after error", t, func() {
+
+		var attempts int
+		var delays []time.Duration
+		last := time.Now()
+
+		out, err := Do(
+			context.Background(),
+			func(context.Context) (string, error) {
+				now := time.Now()
+				delays = append(delays, now.Sub(last))
+				last = now
+				attempts++
+				if attempts == 1 {
+					return "", RetryAfter(errors.New("slow down"), 30*time.Millisecond)
+				}
+				return "ok", nil
+			},
+			OptionBackoff(NewConstantBackoff(time.Hour)),
+		)
+
+		Convey("Then the delay of the error should be used", func() {
+			So(err, ShouldBeNil)
+			So(out, ShouldEqual, "ok")
+			So(attempts, ShouldEqual, 2)
+			So(delays[1], ShouldBeGreaterThanOrEqualTo, 30*time.Millisecond)
+			So(delays[1], ShouldBeLessThan, time.Hour)
+		})
+	})
+
+	Convey("Given I have a job returning a retry after error with a huge delay", t, func() {
+
+		var attempts int
+		start := time.Now()
+
+		out, err := Do(
+			context.Background(),
+			func(context.Context) (string, error) {
+				attempts++
+				if attempts == 1 {
+					return "", RetryAfter(errors.New("slow down"), time.Hour)
+				}
+				return "ok", nil
+			},
+			OptionMaxRetryAfter(30*time.Millisecond),
+		)
+
+		Convey("Then the delay should be capped", func() {
+			So(err, ShouldBeNil)
+			So(out, ShouldEqual, "ok")
+			So(attempts, ShouldEqual, 2)
+			So(time.Since(start), ShouldBeLessThan, time.Hour)
+		})
+	})
+}
+
+func TestParseRetryAfter(t *testing.T) {
+
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	Convey("Given I have various Retry-After values", t, func() {
+
+		Convey("Then seconds should be parsed", func() {
+			d, ok := ParseRetryAfter("120", now)
+			So(ok, ShouldBeTrue)
+			So(d, ShouldEqual, 2*time.Minute)
+		})
+
+		Convey("Then http dates should be parsed", func() {
+			d, ok := ParseRetryAfter("Wed, 01 Jan 2020 00:00:30 GMT", now)
+			So(ok, ShouldBeTrue)
+			So(d, ShouldEqual, 30*time.Second)
+		})
+
+		Convey("Then past http dates should be zero", func() {
+			d, ok := ParseRetryAfter("Tue, 31 Dec 2019 00:00:00 GMT", now)
+			So(ok, ShouldBeTrue)
+			So(d, ShouldEqual, 0)
+		})
+
+		Convey("Then invalid values should be rejected", func() {
+			_, ok := ParseRetryAfter("", now)
+			So(ok, ShouldBeFalse)
+			_, ok = ParseRetryAfter("-1", now)
+			So(ok, ShouldBeFalse)
+			_, ok = ParseRetryAfter("soon", now)
+			So(ok, ShouldBeFalse)
+			_, ok = ParseRetryAfter("-99999999999999999999", now)
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("Then huge values should be clamped", func() {
+			d, ok := ParseRetryAfter("9999999999999", now)
+			So(ok, ShouldBeTrue)
+			So(d, ShouldEqual, time.Duration(math.MaxInt64))
+			d, ok = ParseRetryAfter("99999999999999999999", now)
+			So(ok, ShouldBeTrue)
+			So(d, ShouldEqual, time.Duration(math.MaxInt64))
+		})
+	})
+
+	Convey("Given I have a response with a Retry-After header", t, func() {
+
+		resp := &http.Response{Header: http.Header{"Retry-After": []string{"3"}}}
+		err := WithRetryAfter(resp, errors.New("boom"))
+
+		Convey("Then the error should be wrapped", func() {
+			var raerr *RetryAfterError
+			So(errors.As(err, &raerr), ShouldBeTrue)
+			So(raerr.Delay, ShouldEqual, 3*time.Second)
+			So(err.Error(), ShouldEqual, "boom")
+		})
+	})
+
+	Convey("Given I have a response without Retry-After header", t, func() {
+
+		jobErr := errors.New("boom")
+		err := WithRetryAfter(&http.Response{Header: http.Header{}}, jobErr)
+
+		Convey("Then the error should be returned as is", func() {
+			So(err, ShouldEqual, jobErr)
+		})
+	})
+}
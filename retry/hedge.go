@@ -0,0 +1,157 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	defaultHedgeDelay       = time.Second
+	defaultHedgeMaxAttempts = 2
+)
+
+type hedgeConfig struct {
+	delay       time.Duration
+	maxAttempts int
+	clock       Clock
+}
+
+func newHedgeConfig() hedgeConfig {
+	return hedgeConfig{
+		delay:       defaultHedgeDelay,
+		maxAttempts: defaultHedgeMaxAttempts,
+		clock:       SystemClock(),
+	}
+}
+
+// A HedgeOption can be used to configure Hedge.
+type HedgeOption func(*hedgeConfig)
+
+// HedgeOptionDelay sets how long to wait for the pending
+// attempts before launching a new concurrent one. Default is 1s.
+func HedgeOptionDelay(d time.Duration) HedgeOption {
+	return func(c *hedgeConfig) {
+		c.delay = d
+	}
+}
+
+// HedgeOptionMaxAttempts sets the maximum number of attempts.
+// As attempts can all be running at the same time, it is also the
+// maximum number of concurrent attempts. Default is 2.
+func HedgeOptionMaxAttempts(n int) HedgeOption {
+	return func(c *hedgeConfig) {
+		c.maxAttempts = n
+	}
+}
+
+// HedgeOptionClock sets the Clock used to wait before
+// launching new attempts. Default is SystemClock.
+func HedgeOptionClock(clock Clock) HedgeOption {
+	return func(c *hedgeConfig) {
+		c.clock = clock
+	}
+}
+
+// Hedge calls the given job and, if it has not returned after the
+// configured delay, launches another concurrent attempt, and so on
+// until the maximum number of attempts is reached. If an attempt fails,
+// the next one is launched right away. The output of the first attempt
+// that succeeds is returned, and the context given to the others is
+// canceled. Their outputs are discarded, so the job must honor the
+// context to not leak resources.
+//
+// If all attempts fail, the error of the last one is returned. If an
+// attempt returns an error wrapped with Permanent, Hedge stops right
+// away and returns it.
+//
+// Hedge does not wait between failed attempts. To retry hedged
+// attempts, call Hedge from the job given to Do.
+func Hedge[T any](ctx context.Context, job func(context.Context) (T, error), options ...HedgeOption) (T, error) {
+
+	cfg := newHedgeConfig()
+	for _, opt := range options {
+		opt(&cfg)
+	}
+
+	if cfg.maxAttempts < 1 {
+		cfg.maxAttempts = 1
+	}
+
+	subctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		out T
+		err error
+	}
+
+	// The channel can hold all results so the
+	// goroutines of discarded attempts never block.
+	results := make(chan result, cfg.maxAttempts)
+
+	var launched, pending int
+	launch := func() {
+		launched++
+		pending++
+		go func() {
+			out, err := job(subctx)
+			results <- result{out: out, err: err}
+		}()
+	}
+
+	timer := cfg.clock.NewTimer(cfg.delay)
+	defer timer.Stop()
+
+	launch()
+
+	var zero T
+	var lastErr error
+	for {
+		select {
+
+		case r := <-results:
+			pending--
+
+			if r.err == nil {
+				return r.out, nil
+			}
+
+			lastErr = r.err
+
+			var perr *PermanentError
+			if errors.As(r.err, &perr) {
+				return zero, r.err
+			}
+
+			if launched < cfg.maxAttempts {
+				launch()
+			} else if pending == 0 {
+				return zero, lastErr
+			}
+
+		case <-timer.C():
+			if launched < cfg.maxAttempts {
+				launch()
+				timer.Reset(cfg.delay)
+			}
+
+		case <-ctx.Done():
+			if lastErr != nil {
+				return zero, lastErr
+			}
+			return zero, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHedge(t *testing.T) {
+
+	Convey("Given I have a job whose first attempt is slow", t, func() {
+
+		var calls int32
+		canceled := make(chan struct{})
+
+		out, err := Hedge(
+			context.Background(),
+			func(ctx context.Context) (string, error) {
+				if atomic.AddInt32(&calls, 1) == 1 {
+					<-ctx.Done()
+					close(canceled)
+					return "", ctx.Err()
+				}
+				return "fast", nil
+			},
+			HedgeOptionDelay(10*time.Millisecond),
+		)
+
+		Convey("Then the output of the second attempt should be returned", func() {
+			So(err, ShouldBeNil)
+			So(out, ShouldEqual, "fast")
+			So(atomic.LoadInt32(&calls), ShouldEqual, 2)
+		})
+
+		Convey("Then the first attempt should be canceled", func() {
+			select {
+			case <-canceled:
+			case <-time.After(time.Second):
+				So("first attempt was not canceled", ShouldBeEmpty)
+			}
+		})
+	})
+
+	Convey("Given I have a job that is fast enough", t, func() {
+
+		var calls int32
+
+		out, err := Hedge(
+			context.Background(),
+			func(ctx context.Context) (string, error) {
+				atomic.AddInt32(&calls, 1)
+				return "hello", nil
+			},
+			HedgeOptionDelay(time.Second),
+		)
+
+		Convey("Then a single attempt should be made", func() {
+			So(err, ShouldBeNil)
+			So(out, ShouldEqual, "hello")
+			So(atomic.LoadInt32(&calls), ShouldEqual, 1)
+		})
+	})
+
+	Convey("Given I have a job that is always slow", t, func() {
+
+		var calls, running, maxRunning int32
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		_, err := Hedge(
+			ctx,
+			func(ctx context.Context) (string, error) {
+				atomic.AddInt32(&calls, 1)
+				n := atomic.AddInt32(&running, 1)
+				for {
+					m := atomic.LoadInt32(&maxRunning)
+					if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+						break
+					}
+				}
+				<-ctx.Done()
+				atomic.AddInt32(&running, -1)
+				return "", ctx.Err()
+			},
+			HedgeOptionDelay(5*time.Millisecond),
+			HedgeOptionMaxAttempts(3),
+		)
+
+		Convey("Then the number of concurrent attempts should be capped", func() {
+			So(errors.Is(err, context.DeadlineExceeded), ShouldBeTrue)
+			So(atomic.LoadInt32(&calls), ShouldEqual, 3)
+			So(atomic.LoadInt32(&maxRunning), ShouldEqual, 3)
+		})
+	})
+
+	Convey("Given I have a job that always fails", t, func() {
+
+		var calls int32
+
+		_, err := Hedge(
+			context.Background(),
+			func(ctx context.Context) (string, error) {
+				n := atomic.AddInt32(&calls, 1)
+				return "", errors.New("boom " + string(rune('0'+n)))
+			},
+			HedgeOptionDelay(time.Second),
+			HedgeOptionMaxAttempts(3),
+		)
+
+		Convey("Then all attempts should be made right away and the last error returned", func() {
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "boom 3")
+			So(atomic.LoadInt32(&calls), ShouldEqual, 3)
+		})
+	})
+
+	Convey("Given I have a job that returns a permanent error", t, func() {
+
+		var calls int32
+
+		_, err := Hedge(
+			context.Background(),
+			func(ctx context.Context) (string, error) {
+				atomic.AddInt32(&calls, 1)
+				return "", Permanent(errors.New("nope"))
+			},
+			HedgeOptionMaxAttempts(3),
+		)
+
+		Convey("Then it should stop right away", func() {
+			So(err.Error(), ShouldEqual, "nope")
+			So(atomic.LoadInt32(&calls), ShouldEqual, 1)
+		})
+	})
+
+	Convey("Given I call Hedge from Do", t, func() {
+
+		var calls int32
+
+		out, err := Do(
+			context.Background(),
+			func(ctx context.Context) (string, error) {
+				return Hedge(ctx, func(ctx context.Context) (string, error) {
+					if atomic.AddInt32(&calls, 1) < 4 {
+						return "", errors.New("boom")
+					}
+					return "hello", nil
+				})
+			},
+			OptionBackoff(NewConstantBackoff(time.Millisecond)),
+		)
+
+		Convey("Then hedged attempts should be retried", func() {
+			So(err, ShouldBeNil)
+			So(out, ShouldEqual, "hello")
+			So(atomic.LoadInt32(&calls), ShouldEqual, 4)
+		})
+	})
+}
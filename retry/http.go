@@ -0,0 +1,73 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retry
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRetryAfterSeconds is the largest number of seconds
+// that can be represented as a time.Duration.
+const maxRetryAfterSeconds = int64(math.MaxInt64 / time.Second)
+
+// ParseRetryAfter parses the value of an HTTP Retry-After header,
+// either in seconds or as an HTTP date relative to now. It returns
+// false if the value is empty or invalid. Values too large to be
+// represented as a time.Duration are clamped to the largest one.
+func ParseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil || errors.Is(err, strconv.ErrRange) {
+		if seconds < 0 {
+			return 0, false
+		}
+		if seconds > maxRetryAfterSeconds {
+			return math.MaxInt64, true
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+
+	if d := date.Sub(now); d > 0 {
+		return d, true
+	}
+
+	return 0, true
+}
+
+// WithRetryAfter wraps the given error with RetryAfter if the given
+// response has a valid Retry-After header. Otherwise err is returned.
+func WithRetryAfter(resp *http.Response, err error) error {
+
+	if resp == nil {
+		return err
+	}
+
+	if d, ok := ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+		return RetryAfter(err, d)
+	}
+
+	return err
+}
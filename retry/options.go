@@ -0,0 +1,145 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retry
+
+import "time"
+
+const (
+	defaultDelay         = 3 * time.Second
+	defaultMaxRetryAfter = 10 * time.Minute
+)
+
+type config struct {
+	backoff        Backoff
+	maxAttempts    int
+	maxElapsedTime time.Duration
+	retryFunc      func(error) error
+	breaker        *CircuitBreaker
+	hook           func(Attempt)
+	tracing        bool
+	budget         *Budget
+	budgetWait     bool
+	clock          Clock
+	maxRetryAfter  time.Duration
+}
+
+func newConfig() config {
+	return config{
+		backoff:       NewConstantBackoff(defaultDelay),
+		clock:         SystemClock(),
+		maxRetryAfter: defaultMaxRetryAfter,
+	}
+}
+
+// An Option can be used to configure a retry.
+type Option func(*config)
+
+// OptionBackoff sets the Backoff used to compute the time to wait
+// between attempts. Default is a constant backoff of 3s.
+func OptionBackoff(backoff Backoff) Option {
+	return func(c *config) {
+		c.backoff = backoff
+	}
+}
+
+// OptionRetryFunc sets a function called with the error of
+// each failed attempt before retrying. If it returns an error,
+// the retry procedure stops and that error is returned.
+func OptionRetryFunc(retryFunc func(error) error) Option {
+	return func(c *config) {
+		c.retryFunc = retryFunc
+	}
+}
+
+// OptionMaxAttempts sets the maximum number of attempts.
+// When reached, an *ExhaustedError is returned.
+// Default is 0, meaning no limit.
+func OptionMaxAttempts(attempts int) Option {
+	return func(c *config) {
+		c.maxAttempts = attempts
+	}
+}
+
+// OptionMaxElapsedTime sets the maximum time spent retrying,
+// independently from the context. When the next attempt would
+// start after that time, an *ExhaustedError is returned.
+// Default is 0, meaning no limit.
+func OptionMaxElapsedTime(d time.Duration) Option {
+	return func(c *config) {
+		c.maxElapsedTime = d
+	}
+}
+
+// OptionCircuitBreaker sets the CircuitBreaker guarding the attempts.
+// The same breaker can be given to several retries so they all fail
+// fast with ErrCircuitOpen when the dependency is down.
+// Default is no circuit breaker.
+func OptionCircuitBreaker(breaker *CircuitBreaker) Option {
+	return func(c *config) {
+		c.breaker = breaker
+	}
+}
+
+// OptionHook sets a function called with the details of
+// each failed attempt, right before waiting for the next one.
+// It is not called when no other attempt will be made.
+func OptionHook(hook func(Attempt)) Option {
+	return func(c *config) {
+		c.hook = hook
+	}
+}
+
+// OptionTracing enables logging an event for each attempt in
+// the opentracing span carried by the context, if any.
+func OptionTracing() Option {
+	return func(c *config) {
+		c.tracing = true
+	}
+}
+
+// OptionBudget sets the Budget consulted before each retry.
+// When it is exhausted, the retry procedure stops and an error
+// wrapping ErrBudgetExhausted is returned.
+// Default is no budget.
+func OptionBudget(budget *Budget) Option {
+	return func(c *config) {
+		c.budget = budget
+		c.budgetWait = false
+	}
+}
+
+// OptionBudgetWait works like OptionBudget, but when the budget
+// is exhausted, the next attempt waits until a token is available.
+func OptionBudgetWait(budget *Budget) Option {
+	return func(c *config) {
+		c.budget = budget
+		c.budgetWait = true
+	}
+}
+
+// OptionClock sets the Clock used to measure the elapsed
+// time and to wait between attempts. Default is SystemClock.
+func OptionClock(clock Clock) Option {
+	return func(c *config) {
+		c.clock = clock
+	}
+}
+
+// OptionMaxRetryAfter sets the maximum delay honored when an attempt
+// fails with a *RetryAfterError. Longer delays are reduced to it, so a
+// misbehaving server cannot stall the retry procedure for too long.
+// Default is 10m. A value of 0 or less means no limit.
+func OptionMaxRetryAfter(d time.Duration) Option {
+	return func(c *config) {
+		c.maxRetryAfter = d
+	}
+}
@@ -13,6 +13,8 @@ package retry
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 )
 
@@ -21,27 +23,184 @@ import (
 // retryFunc will get the error that caused the retry. If retryFunc
 // retries an error, then the retry procedure stops, and the error is
 // returned.
+// Attempts are spaced by 3s.
 func Retry(
 	ctx context.Context,
 	jobFunc func() (interface{}, error),
 	retryFunc func(error) error,
 ) (out interface{}, err error) {
 
-	for {
-		if out, err = jobFunc(); err == nil {
-			return out, nil
+	return RetryWithOptions(
+		ctx,
+		jobFunc,
+		retryFunc,
+		OptionBackoff(NewConstantBackoff(defaultDelay)),
+	)
+}
+
+// RetryWithOptions works like Retry, but can be
+// configured using the given options.
+func RetryWithOptions(
+	ctx context.Context,
+	jobFunc func() (interface{}, error),
+	retryFunc func(error) error,
+	options ...Option,
+) (out interface{}, err error) {
+
+	return Do(
+		ctx,
+		func(context.Context) (interface{}, error) { return jobFunc() },
+		append([]Option{OptionRetryFunc(retryFunc)}, options...)...,
+	)
+}
+
+// Do calls the given job until it succeeds or the context is done,
+// then returns its output. The context is passed to the job so each
+// attempt can be canceled. If the context is done before the job
+// succeeds, the error returned by the last attempt is returned.
+//
+// If the job returns an error wrapped with Permanent, Do stops
+// immediately and returns it. If the job returns an error wrapped
+// with RetryAfter, the next attempt happens after the given delay,
+// capped by OptionMaxRetryAfter, instead of the one computed by the
+// backoff.
+//
+// If a CircuitBreaker is given using OptionCircuitBreaker, each attempt
+// is first allowed by the breaker, and its outcome is reported to it.
+// When the breaker is open, Do stops immediately and returns ErrCircuitOpen.
+//
+// If a Budget is given using OptionBudget or OptionBudgetWait, each
+// retry takes a token from it. When it is exhausted, Do either stops
+// and returns an error wrapping ErrBudgetExhausted, or waits until a
+// token is available.
+//
+// If a hook is given using OptionHook, it is called with the details
+// of each failed attempt before waiting for the next one. If tracing
+// is enabled using OptionTracing, an event is logged for each attempt
+// in the span carried by the context.
+//
+// Do can be configured using the given options.
+func Do[T any](ctx context.Context, job func(context.Context) (T, error), options ...Option) (T, error) {
+
+	cfg := newConfig()
+	for _, opt := range options {
+		opt(&cfg)
+	}
+
+	var zero T
+	start := cfg.clock.Now()
+
+	var delay time.Duration
+	for attempt := 1; ; attempt++ {
+
+		if cfg.breaker != nil {
+			if berr := cfg.breaker.Allow(); berr != nil {
+				return zero, berr
+			}
 		}
 
-		if retryFunc != nil {
-			if rerr := retryFunc(err); rerr != nil {
-				return nil, rerr
+		out, err := job(ctx)
+		if cfg.breaker != nil {
+			report(ctx, cfg.breaker, err)
+		}
+
+		a := Attempt{Number: attempt, Err: err, Elapsed: cfg.clock.Now().Sub(start)}
+
+		if err == nil {
+			if cfg.tracing {
+				traceAttempt(ctx, a)
 			}
+			return out, nil
+		}
+
+		var serr error
+		delay, serr = cfg.next(a, delay)
+		if serr == nil {
+			a.NextDelay = delay
+		}
+
+		if cfg.tracing {
+			traceAttempt(ctx, a)
+		}
+
+		if serr != nil {
+			return zero, serr
 		}
 
+		if cfg.hook != nil {
+			cfg.hook(a)
+		}
+
+		timer := cfg.clock.NewTimer(delay)
 		select {
-		case <-time.After(3 * time.Second):
+		case <-timer.C():
 		case <-ctx.Done():
-			return nil, err
+			timer.Stop()
+			return zero, err
+		}
+	}
+}
+
+// next returns the delay to wait after the given failed attempt,
+// or the error to return if no other attempt must be made.
+func (c config) next(a Attempt, previous time.Duration) (time.Duration, error) {
+
+	var perr *PermanentError
+	if errors.As(a.Err, &perr) {
+		return 0, a.Err
+	}
+
+	if c.maxAttempts > 0 && a.Number >= c.maxAttempts {
+		return 0, &ExhaustedError{Attempts: a.Number, Elapsed: a.Elapsed, Err: a.Err}
+	}
+
+	if c.retryFunc != nil {
+		if rerr := c.retryFunc(a.Err); rerr != nil {
+			return 0, rerr
+		}
+	}
+
+	var delay time.Duration
+	var raerr *RetryAfterError
+	if errors.As(a.Err, &raerr) {
+		delay = raerr.Delay
+		if c.maxRetryAfter > 0 && delay > c.maxRetryAfter {
+			delay = c.maxRetryAfter
 		}
+	} else {
+		delay = c.backoff.Delay(a.Number, previous)
+	}
+
+	if c.budget != nil {
+		if c.budgetWait {
+			if wait := c.budget.Reserve(); wait > delay {
+				delay = wait
+			}
+		} else if !c.budget.Allow() {
+			return 0, fmt.Errorf("%w: %s", ErrBudgetExhausted, a.Err)
+		}
+	}
+
+	if c.maxElapsedTime > 0 && a.Elapsed+delay > c.maxElapsedTime {
+		return 0, &ExhaustedError{Attempts: a.Number, Elapsed: a.Elapsed, Err: a.Err}
+	}
+
+	return delay, nil
+}
+
+// report reports the outcome of an attempt to the given breaker.
+// Permanent errors and errors caused by the context being
+// done are not counted as failures of the dependency.
+func report(ctx context.Context, breaker *CircuitBreaker, err error) {
+
+	var perr *PermanentError
+
+	switch {
+	case err == nil:
+		breaker.Success()
+	case errors.As(err, &perr), ctx.Err() != nil:
+		breaker.release()
+	default:
+		breaker.Failure()
 	}
 }
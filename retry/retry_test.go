@@ -0,0 +1,207 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRetry(t *testing.T) {
+
+	Convey("Given I have a job that succeeds", t, func() {
+
+		out, err := Retry(
+			context.Background(),
+			func() (interface{}, error) { return "hello", nil },
+			nil,
+		)
+
+		Convey("Then the output should be returned", func() {
+			So(err, ShouldBeNil)
+			So(out, ShouldEqual, "hello")
+		})
+	})
+
+	Convey("Given I have a job that fails and a retryFunc that stops", t, func() {
+
+		out, err := Retry(
+			context.Background(),
+			func() (interface{}, error) { return nil, errors.New("boom") },
+			func(err error) error { return errors.New("stop: " + err.Error()) },
+		)
+
+		Convey("Then the error of retryFunc should be returned", func() {
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "stop: boom")
+			So(out, ShouldBeNil)
+		})
+	})
+}
+
+func TestRetryWithOptions(t *testing.T) {
+
+	Convey("Given I have a job that succeeds after some attempts", t, func() {
+
+		var attempts int
+		var delays []time.Duration
+		last := time.Now()
+
+		out, err := RetryWithOptions(
+			context.Background(),
+			func() (interface{}, error) {
+				now := time.Now()
+				delays = append(delays, now.Sub(last))
+				last = now
+				attempts++
+				if attempts < 4 {
+					return nil, errors.New("boom")
+				}
+				return "hello", nil
+			},
+			nil,
+			OptionBackoff(NewLinearBackoff(10*time.Millisecond, 10*time.Millisecond)),
+		)
+
+		Convey("Then the output should be returned", func() {
+			So(err, ShouldBeNil)
+			So(out, ShouldEqual, "hello")
+			So(attempts, ShouldEqual, 4)
+		})
+
+		Convey("Then the backoff should be used", func() {
+			So(delays[1], ShouldBeGreaterThanOrEqualTo, 10*time.Millisecond)
+			So(delays[2], ShouldBeGreaterThanOrEqualTo, 20*time.Millisecond)
+			So(delays[3], ShouldBeGreaterThanOrEqualTo, 30*time.Millisecond)
+		})
+	})
+
+	Convey("Given I have a job that always fails", t, func() {
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		var retried int
+		out, err := RetryWithOptions(
+			ctx,
+			func() (interface{}, error) { return nil, errors.New("boom") },
+			func(error) error { retried++; return nil },
+			OptionBackoff(NewConstantBackoff(5*time.Millisecond)),
+		)
+
+		Convey("Then the last error should be returned when the context is done", func() {
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "boom")
+			So(out, ShouldBeNil)
+			So(retried, ShouldBeGreaterThan, 1)
+		})
+	})
+}
+
+func TestRetryWithOptions_Limits(t *testing.T) {
+
+	Convey("Given I have a job that always fails", t, func() {
+
+		jobErr := errors.New("boom")
+
+		var attempts int
+		job := func() (interface{}, error) { attempts++; return nil, jobErr }
+
+		Convey("When I retry with a maximum number of attempts", func() {
+
+			var retried int
+			out, err := RetryWithOptions(
+				context.Background(),
+				job,
+				func(error) error { retried++; return nil },
+				OptionBackoff(NewConstantBackoff(time.Millisecond)),
+				OptionMaxAttempts(3),
+			)
+
+			Convey("Then an *ExhaustedError should be returned", func() {
+				So(out, ShouldBeNil)
+				var eerr *ExhaustedError
+				So(errors.As(err, &eerr), ShouldBeTrue)
+				So(eerr.Attempts, ShouldEqual, 3)
+				So(errors.Is(err, jobErr), ShouldBeTrue)
+				So(err.Error(), ShouldStartWith, "retry exhausted after 3 attempts in ")
+				So(err.Error(), ShouldEndWith, ": boom")
+			})
+
+			Convey("Then the job should have been attempted 3 times", func() {
+				So(attempts, ShouldEqual, 3)
+				So(retried, ShouldEqual, 2)
+			})
+		})
+
+		Convey("When I retry with a maximum elapsed time", func() {
+
+			start := time.Now()
+			out, err := RetryWithOptions(
+				context.Background(),
+				job,
+				nil,
+				OptionBackoff(NewConstantBackoff(20*time.Millisecond)),
+				OptionMaxElapsedTime(50*time.Millisecond),
+			)
+
+			Convey("Then an *ExhaustedError should be returned before the deadline", func() {
+				So(out, ShouldBeNil)
+				var eerr *ExhaustedError
+				So(errors.As(err, &eerr), ShouldBeTrue)
+				So(eerr.Attempts, ShouldBeGreaterThanOrEqualTo, 2)
+				So(eerr.Err, ShouldEqual, jobErr)
+				So(time.Since(start), ShouldBeLessThan, 50*time.Millisecond)
+			})
+		})
+	})
+}
+
+func TestDo(t *testing.T) {
+
+	Convey("Given I have a typed job that succeeds after some attempts", t, func() {
+
+		type key struct{}
+		ctx := context.WithValue(context.Background(), key{}, "value")
+
+		var attempts int
+		out, err := Do(
+			ctx,
+			func(ctx context.Context) (int, error) {
+				if ctx.Value(key{}) != "value" {
+					panic("expected the context to be passed")
+				}
+				attempts++
+				if attempts < 3 {
+					return 0, errors.New("boom")
+				}
+				return 42, nil
+			},
+			OptionBackoff(NewConstantBackoff(time.Millisecond)),
+		)
+
+		Convey("Then the typed output should be returned", func() {
+			So(err, ShouldBeNil)
+			So(out, ShouldEqual, 42)
+			So(attempts, ShouldEqual, 3)
+		})
+	})
+
+	Convey("Given I have a typed job that always fails", t, func() {
+
+		out, err := Do(
+			context.Background(),
+			func(context.Context) (*int, error) { return nil, errors.New("boom") },
+			OptionBackoff(NewConstantBackoff(time.Millisecond)),
+			OptionRetryFunc(func(err error) error { return errors.New("stop") }),
+		)
+
+		Convey("Then the zero value and the error should be returned", func() {
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "stop")
+			So(out, ShouldBeNil)
+		})
+	})
+}